@@ -7,10 +7,20 @@ import (
 
 	"github.com/akamensky/argparse"
 	"github.com/compspec/compspec-go/cmd/compspec/create"
+	"github.com/compspec/compspec-go/cmd/compspec/diff"
 	"github.com/compspec/compspec-go/cmd/compspec/extract"
 	"github.com/compspec/compspec-go/cmd/compspec/list"
 	"github.com/compspec/compspec-go/cmd/compspec/match"
+	"github.com/compspec/compspec-go/cmd/compspec/query"
+	"github.com/compspec/compspec-go/cmd/compspec/schema"
+	"github.com/compspec/compspec-go/pkg/config"
+	"github.com/compspec/compspec-go/pkg/plugin"
 	"github.com/compspec/compspec-go/pkg/types"
+	"github.com/compspec/compspec-go/plugins/extractors/declarative"
+	"github.com/compspec/compspec-go/plugins/extractors/external"
+
+	// Register the plugins that are compiled into compspec
+	_ "github.com/compspec/compspec-go/plugins/builtin"
 )
 
 // I know this text is terrible, just having fun for now
@@ -34,16 +44,60 @@ func main() {
 	listCmd := parser.NewCommand("list", "List plugins and known sections")
 	createCmd := parser.NewCommand("create", "Create a compatibility artifact for the current host according to a definition")
 	matchCmd := parser.NewCommand("match", "Match a manifest of container images / artifact pairs against a set of host fields")
+	queryCmd := parser.NewCommand("query", "Find fields (and values) in an extraction result, or for this host")
+	diffCmd := parser.NewCommand("diff", "Compare two extraction results (or compatibility artifacts) field by field")
+	schemaCmd := parser.NewCommand("schema", "Show the JSON Schema for an extractor, or validate a result against it")
 
 	// Shared arguments (likely this will break into check and extract, shared for now)
 	pluginNames := parser.StringList("n", "name", &argparse.Options{Help: "One or more specific plugins to target names"})
+	pluginDirs := parser.StringList("", "plugin-dir", &argparse.Options{Help: "One or more directories with external extractors (compspec-extractor-<name>)"})
+	optionSpecs := parser.StringList("", "option", &argparse.Options{Help: "One or more plugin options as <plugin>.<option>=<value> (e.g., library.mpi.exec=/opt/mpi/bin/mpirun)"})
+	configFile := parser.String("", "config", &argparse.Options{Help: "A yaml config file, used after the system, user, and project config (or COMPSPEC_CONFIG)"})
+	profile := parser.String("", "profile", &argparse.Options{Help: "A named profile from the config (or COMPSPEC_PROFILE), e.g., rainbow-node"})
 
 	// Extract arguments
-	filename := extractCmd.String("o", "out", &argparse.Options{Help: "Save extraction to json file"})
+	filename := extractCmd.String("o", "out", &argparse.Options{Help: "Save extraction to a file (json by default), or - for stdout"})
+	format := extractCmd.Selector("", "format", plugin.Formats, &argparse.Options{Help: "Format to save extraction in (json, yaml, flat, env, or ndjson), which is written to stdout without --out"})
+	excludes := extractCmd.StringList("x", "exclude", &argparse.Options{Help: "One or more plugins, sections, or fields to leave out (e.g., kernel, system[processor], or nfd[cpu:cpuid.AVX*])"})
 	allowFail := extractCmd.Flag("f", "allow-fail", &argparse.Options{Help: "Allow any specific extractor to fail (and continue extraction)"})
+	jobs := extractCmd.Int("j", "jobs", &argparse.Options{Help: "Number of plugins or sections to extract at once (defaults to the number of processors, 1 is serial)"})
+	timeouts := extractCmd.StringList("t", "timeout", &argparse.Options{Help: "Timeout for the extraction (e.g., 2m), or a plugin or section (e.g., library=30s or library[mpi]=5s)"})
+	root := extractCmd.String("", "root", &argparse.Options{Help: "Extract from files under this root (e.g., a container rootfs) instead of the host"})
+	imagePath := extractCmd.String("", "image", &argparse.Options{Help: "Extract from an image in an OCI layout (directory or tarball) or docker save archive"})
+	platform := extractCmd.String("", "platform", &argparse.Options{Help: "Platform of the image to extract from (e.g., linux/arm64), defaults to this one"})
+	redactRules := extractCmd.StringList("", "redact", &argparse.Options{Help: "One or more fields to redact as <field>[=<action>], where the field is a glob (e.g., kernel.boot.*) and the action is drop (default), hash, or mask"})
+	redactFile := extractCmd.String("", "redact-file", &argparse.Options{Help: "A yaml file with redaction rules (used after --redact)"})
+	validate := extractCmd.Flag("", "validate", &argparse.Options{Help: "Check the extracted fields against the schema of each extractor"})
+
+	// List arguments
+	listTarget := listCmd.StringPositional(&argparse.Options{Help: "A plugin (and sections) to list fields for, e.g., system[os,arch]"})
+	listFields := listCmd.Flag("", "fields", &argparse.Options{Help: "List the fields that extractors return, with a type and description"})
+	listJson := listCmd.Flag("", "json", &argparse.Options{Help: "Print the fields as json"})
+
+	// Schema subcommands and arguments
+	extractorSchemaCmd := schemaCmd.NewCommand("extractor", "Print the JSON Schema for the data of an extractor")
+	validateSchemaCmd := schemaCmd.NewCommand("validate", "Check an extraction result against the schema of each extractor")
+	schemaExtractor := extractorSchemaCmd.StringPositional(&argparse.Options{Help: "The name of the extractor"})
+	schemaResult := validateSchemaCmd.StringPositional(&argparse.Options{Help: "The extraction result to validate"})
+
+	// Diff arguments
+	oldFile := diffCmd.StringPositional(&argparse.Options{Help: "The old extraction result or artifact"})
+	newFile := diffCmd.StringPositional(&argparse.Options{Help: "The new extraction result or artifact"})
+	ignoreFields := diffCmd.StringList("", "ignore", &argparse.Options{Help: "One or more fields to ignore, as a glob for the field (e.g., system.processor.*) or its last part (e.g., cpu_mhz)"})
+	diffJson := diffCmd.Flag("", "json", &argparse.Options{Help: "Print the changes as json"})
+	diffValidate := diffCmd.Flag("", "validate", &argparse.Options{Help: "Check extraction results against the schema of each extractor first"})
+
+	// Query arguments
+	querySource := queryCmd.StringPositional(&argparse.Options{Help: "An extraction result, or 'live' to extract from this host"})
+	queryPattern := queryCmd.StringPositional(&argparse.Options{Help: "Fields to find as <extractor>.<section>.<field>, where each part can be a glob"})
+	queryFilters := queryCmd.StringList("w", "where", &argparse.Options{Help: "One or more comparisons for the value (e.g., '>=4.1' or '=Intel*')"})
+	distinct := queryCmd.Flag("d", "distinct", &argparse.Options{Help: "Show each distinct value (and how many fields have it)"})
+	count := queryCmd.Flag("c", "count", &argparse.Options{Help: "Show the number of fields (or distinct values) only"})
+	queryJson := queryCmd.Flag("", "json", &argparse.Options{Help: "Print the result as json"})
+	queryValidate := queryCmd.Flag("", "validate", &argparse.Options{Help: "Check the extraction result against the schema of each extractor first"})
 
 	// Match arguments
-	matchFields := matchCmd.StringList("m", "match", &argparse.Options{Help: "One or more key value pairs to match"})
+	matchFields := matchCmd.StringList("m", "match", &argparse.Options{Help: "One or more key value pairs to match (or compare with >=, <=, >, <, !=)"})
 	manifestFile := matchCmd.String("i", "in", &argparse.Options{Required: true, Help: "Input manifest list yaml that contains pairs of images and artifacts"})
 	printMapping := matchCmd.Flag("p", "print", &argparse.Options{Help: "Print mapping of images to attributes only."})
 	printGraph := matchCmd.Flag("", "print-graph", &argparse.Options{Help: "Print schema graph"})
@@ -64,6 +118,9 @@ func main() {
 	specfile := artifactCmd.String("o", "out", &argparse.Options{Help: "Save compatibility json artifact to this file"})
 	mediaType := artifactCmd.String("m", "media-type", &argparse.Options{Help: "The expected media-type for the compatibility artifact"})
 	allowFailCreate := artifactCmd.Flag("f", "allow-fail", &argparse.Options{Help: "Allow any specific extractor to fail (and continue extraction)"})
+	rootCreate := artifactCmd.String("", "root", &argparse.Options{Help: "Extract from files under this root (e.g., a container rootfs) instead of the host"})
+	imageCreate := artifactCmd.String("", "image", &argparse.Options{Help: "Extract from an image in an OCI layout (directory or tarball) or docker save archive"})
+	platformCreate := artifactCmd.String("", "platform", &argparse.Options{Help: "Platform of the image to extract from (e.g., linux/arm64), defaults to this one"})
 
 	// Nodes creation arguments
 	nodesOutFile := nodesCmd.String("", "nodes-output", &argparse.Options{Help: "Output json file for cluster nodes"})
@@ -78,14 +135,28 @@ func main() {
 		return
 	}
 
+	// External extractors can be on the PATH or in plugin directories,
+	// and extractors can also be defined in YAML in plugin directories
+	external.Register(*pluginDirs)
+	err = declarative.Register(*pluginDirs)
+	if err != nil {
+		log.Fatalf("Issue loading extractor definitions: %s\n", err)
+	}
+
+	// Config files are layered, and a profile is used on top of them
+	settings, err := config.Resolve(*configFile, *profile)
+	if err != nil {
+		log.Fatalf("Issue loading config: %s\n", err)
+	}
+
 	if extractCmd.Happened() {
-		err := extract.Run(*filename, *pluginNames, *allowFail)
+		err := extract.Run(*filename, *pluginNames, *excludes, *optionSpecs, settings, *allowFail, *timeouts, *jobs, *root, *imagePath, *platform, *format, *redactRules, *redactFile, *validate)
 		if err != nil {
 			log.Fatalf("Issue with extraction: %s\n", err)
 		}
 	} else if createCmd.Happened() {
 		if artifactCmd.Happened() {
-			err := create.Artifact(*specname, *options, *specfile, *allowFailCreate)
+			err := create.Artifact(*specname, *options, *specfile, *allowFailCreate, *rootCreate, *imageCreate, *platformCreate, *optionSpecs, settings)
 			if err != nil {
 				log.Fatal(err.Error())
 			}
@@ -100,6 +171,14 @@ func main() {
 		}
 
 	} else if matchCmd.Happened() {
+
+		// The config can have a cache and media type for artifacts
+		if *mediaType == "" {
+			*mediaType = settings.MediaType
+		}
+		if *cachePath == "" {
+			*cachePath = settings.Cache
+		}
 		err := match.Run(
 			*manifestFile,
 			*matchFields,
@@ -116,8 +195,43 @@ func main() {
 		if err != nil {
 			log.Fatal(err.Error())
 		}
+	} else if queryCmd.Happened() {
+		err := query.Run(*querySource, *queryPattern, *queryFilters, *pluginNames, *optionSpecs, settings, *distinct, *count, *queryJson, *queryValidate)
+		if err != nil {
+			log.Fatalf("Issue with query: %s\n", err)
+		}
+	} else if diffCmd.Happened() {
+
+		// Like diff, the exit code is 1 if there are changes, and 2 for an error
+		changed, err := diff.Run(*oldFile, *newFile, *ignoreFields, *diffJson, *diffValidate)
+		if err != nil {
+			log.Printf("Issue with diff: %s\n", err)
+			os.Exit(2)
+		}
+		if changed {
+			os.Exit(1)
+		}
+	} else if schemaCmd.Happened() {
+		if extractorSchemaCmd.Happened() {
+			err := schema.Extractor(*schemaExtractor)
+			if err != nil {
+				log.Fatal(err.Error())
+			}
+		} else if validateSchemaCmd.Happened() {
+			err := schema.Validate(*schemaResult)
+			if err != nil {
+				log.Fatal(err.Error())
+			}
+		}
 	} else if listCmd.Happened() {
-		err := list.Run(*pluginNames)
+		names := *pluginNames
+		if *listTarget != "" {
+			names = append(names, *listTarget)
+		}
+		if len(names) == 0 {
+			names = settings.Names
+		}
+		err := list.Run(names, *listFields, *listJson)
 		if err != nil {
 			log.Fatal(err.Error())
 		}
@@ -1,13 +1,24 @@
 package create
 
 import (
+	"github.com/compspec/compspec-go/pkg/config"
 	"github.com/compspec/compspec-go/pkg/plugin"
 	"github.com/compspec/compspec-go/plugins/creators/artifact"
 )
 
 // Artifact will create a compatibility artifact based on a request in YAML
 // TODO likely want to refactor this into a proper create plugin
-func Artifact(specname string, fields []string, saveto string, allowFail bool) error {
+func Artifact(
+	specname string,
+	fields []string,
+	saveto string,
+	allowFail bool,
+	root string,
+	image string,
+	platform string,
+	optionSpecs []string,
+	settings config.Settings,
+) error {
 
 	// assemble options for node creator
 	creator, err := artifact.NewPlugin()
@@ -19,12 +30,18 @@ func Artifact(specname string, fields []string, saveto string, allowFail bool) e
 		StrOpts: map[string]string{
 			"specname": specname,
 			"saveto":   saveto,
+			"root":     root,
+			"image":    image,
+			"platform": platform,
 		},
 		BoolOpts: map[string]bool{
 			"allowFail": allowFail,
 		},
 		ListOpts: map[string][]string{
-			"fields": fields,
+			"fields":  fields,
+			"options": append(settings.OptionSpecs(), optionSpecs...),
+			"schemas": settings.SchemaSpecs(),
+			"redact":  settings.RedactSpecs(),
 		},
 	}
 	return creator.Create(options)
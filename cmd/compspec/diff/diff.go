@@ -0,0 +1,29 @@
+package diff
+
+import (
+	"fmt"
+
+	"github.com/compspec/compspec-go/pkg/diff"
+)
+
+// Run compares two extraction results (or compatibility artifacts) and
+// returns true if they are different
+func Run(oldPath, newPath string, ignore []string, asJson, validate bool) (bool, error) {
+	if oldPath == "" || newPath == "" {
+		return false, fmt.Errorf("please provide two files to compare")
+	}
+	report, err := diff.Files(oldPath, newPath, ignore, validate)
+	if err != nil {
+		return false, err
+	}
+	if asJson {
+		b, err := report.ToJson()
+		if err != nil {
+			return false, fmt.Errorf("there was an issue marshalling to JSON: %s", err)
+		}
+		fmt.Println(string(b))
+	} else {
+		report.Print()
+	}
+	return report.HasChanges(), nil
+}
@@ -1,16 +1,47 @@
 package extract
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"os"
+	"os/signal"
 	"runtime"
+	"syscall"
 
+	"github.com/compspec/compspec-go/pkg/config"
+	"github.com/compspec/compspec-go/pkg/image"
+	"github.com/compspec/compspec-go/pkg/redact"
 	p "github.com/compspec/compspec-go/plugins"
 )
 
 // Run will run an extraction of host metadata
-func Run(filename string, pluginNames []string, allowFail bool) error {
-	fmt.Printf("⭐️ Running extract...\n")
+func Run(
+	filename string,
+	pluginNames []string,
+	excludes []string,
+	optionSpecs []string,
+	settings config.Settings,
+	allowFail bool,
+	timeoutSpecs []string,
+	jobs int,
+	rootPath string,
+	imagePath string,
+	platform string,
+	format string,
+	redactRules []string,
+	redactFile string,
+	validate bool,
+) error {
+
+	// Writing to stdout, our messages go to stderr so the output can be
+	// piped (and plugins always write messages to stderr)
+	var progress io.Writer = os.Stdout
+	if filename == "-" || (filename == "" && format != "") {
+		filename = "-"
+		progress = os.Stderr
+	}
+	fmt.Fprintf(progress, "⭐️ Running extract...\n")
 
 	// Womp womp, we only support linux! There is no other way.
 	operatingSystem := runtime.GOOS
@@ -18,28 +49,83 @@ func Run(filename string, pluginNames []string, allowFail bool) error {
 		return fmt.Errorf("🤓️ sorry, we only support linux")
 	}
 
+	// A timeout can be global, or for a plugin or plugin section
+	timeouts, err := p.ParseTimeouts(timeoutSpecs)
+	if err != nil {
+		return err
+	}
+
+	// Fields can be redacted before the result is printed or saved
+	policy, err := redact.NewPolicy(redactRules, redactFile)
+	if err != nil {
+		return err
+	}
+	policy.Rules = append(policy.Rules, settings.Redact...)
+
+	// Files can be read from another root (e.g., a container rootfs)
+	// or from an image, which we unpack first
+	root, cleanup, err := image.OpenRoot(rootPath, imagePath, platform)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
 	// parse [section,...,section] into named plugins and sections
 	// return plugins
-	plugins, err := p.GetPlugins(pluginNames)
+	// A config (or profile) can say what to extract, and what to leave out
+	if len(pluginNames) == 0 {
+		pluginNames = settings.Names
+	}
+	excludes = append(append([]string{}, settings.Exclude...), excludes...)
+	plugins, err := p.SelectPlugins(pluginNames, excludes)
+	if err != nil {
+		return err
+	}
+	err = plugins.SetTimeouts(timeouts)
 	if err != nil {
 		return err
 	}
 
-	// Extract data for all plugins
-	result, err := plugins.Extract(allowFail)
+	// Options for plugins, from the config and the command line
+	raw, err := settings.PluginOptions(optionSpecs)
+	if err != nil {
+		return err
+	}
+	err = plugins.SetOptions(raw)
 	if err != nil {
 		return err
 	}
 
-	// If a filename is provided, save to json
-	if filename != "" {
+	// Stop extraction (and kill commands) on interrupt or the global timeout
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if timeouts.Global > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeouts.Global)
+		defer cancel()
+	}
 
-		// This returns an array of bytes
-		b, err := result.ToJson()
+	// Extract data for all plugins, using all processors by default
+	if jobs == 0 {
+		jobs = runtime.NumCPU()
+	}
+	options := p.ExtractOptions{AllowFail: allowFail, Jobs: jobs, Root: root, Image: imagePath, Redact: policy, Validate: validate}
+	result, err := plugins.Extract(ctx, options)
+	if err != nil {
+		return err
+	}
+
+	// If a filename is provided, save in the format (json by default)
+	if filename != "" {
+		b, err := result.Format(format)
 		if err != nil {
-			return fmt.Errorf("there was an issue marshalling to JSON: %s", err)
+			return fmt.Errorf("there was an issue writing %s: %s", format, err)
+		}
+		if filename == "-" {
+			_, err = os.Stdout.Write(b)
+		} else {
+			err = os.WriteFile(filename, b, 0644)
 		}
-		err = os.WriteFile(filename, b, 0644)
 		if err != nil {
 			return err
 		}
@@ -47,6 +133,6 @@ func Run(filename string, pluginNames []string, allowFail bool) error {
 		result.Print()
 	}
 
-	fmt.Println("Extraction has run!")
+	fmt.Fprintln(progress, "Extraction has run!")
 	return nil
 }
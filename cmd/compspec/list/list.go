@@ -4,14 +4,19 @@ import (
 	p "github.com/compspec/compspec-go/plugins"
 )
 
-// Run will list the extractor names and sections known
-func Run(pluginNames []string) error {
+// Run will list the extractor names and sections known, or the fields
+// that extractors return
+func Run(pluginNames []string, fields bool, asJson bool) error {
 	// parse [section,...,section] into named plugins and sections
 	// return plugins
 	plugins, err := p.GetPlugins(pluginNames)
 	if err != nil {
 		return err
 	}
+	if fields {
+		return plugins.ListFields(asJson)
+	}
+
 	// List plugin table
 	return plugins.List()
 }
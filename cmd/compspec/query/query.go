@@ -0,0 +1,120 @@
+package query
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"runtime"
+
+	"github.com/compspec/compspec-go/pkg/config"
+	"github.com/compspec/compspec-go/pkg/plugin"
+	"github.com/compspec/compspec-go/pkg/query"
+	"github.com/compspec/compspec-go/pkg/schema"
+	p "github.com/compspec/compspec-go/plugins"
+)
+
+// LiveSource is the source to extract from the host instead of a file
+const LiveSource = "live"
+
+// Run queries an extraction result (or the host) for fields. A result
+// can be checked against the schema of each extractor first.
+func Run(
+	source string,
+	pattern string,
+	filters []string,
+	pluginNames []string,
+	optionSpecs []string,
+	settings config.Settings,
+	distinct bool,
+	count bool,
+	asJson bool,
+	validate bool,
+) error {
+	if source == "" || pattern == "" {
+		return fmt.Errorf("please provide a result file (or %s) and a field pattern", LiveSource)
+	}
+	q, err := query.New(pattern, filters)
+	if err != nil {
+		return err
+	}
+	if source != LiveSource && (len(pluginNames) > 0 || len(optionSpecs) > 0) {
+		return fmt.Errorf("plugins (-n) and options (--option) are only for a query of %s", LiveSource)
+	}
+	result, err := load(source, q, pluginNames, optionSpecs, settings, validate)
+	if err != nil {
+		return err
+	}
+	rows := q.Run(result)
+
+	// What we show depends on the aggregation
+	var out interface{} = rows
+	switch {
+	case distinct && count:
+		out = map[string]int{"count": len(query.Distinct(rows))}
+	case count:
+		out = map[string]int{"count": len(rows)}
+	case distinct:
+		out = query.Distinct(rows)
+	}
+	if asJson {
+		b, err := json.MarshalIndent(out, "", "  ")
+		if err != nil {
+			return fmt.Errorf("there was an issue marshalling to JSON: %s", err)
+		}
+		fmt.Println(string(b))
+		return nil
+	}
+	switch out := out.(type) {
+	case map[string]int:
+		fmt.Println(out["count"])
+	case []query.Count:
+		for _, c := range out {
+			fmt.Printf("%7d %s\n", c.Count, c.Value)
+		}
+	case []query.Row:
+		for _, row := range out {
+			fmt.Printf("%s: %s\n", row.Field, row.Value)
+		}
+	}
+	return nil
+}
+
+// load reads a result from a file, or extracts what the query needs. For
+// the host, plugins (and sections) can be named instead, and options and
+// exclusions come from the config and the command line, as for extract.
+func load(source string, q query.Query, pluginNames, optionSpecs []string, settings config.Settings, validate bool) (plugin.Result, error) {
+	result := plugin.Result{}
+	if source != LiveSource && validate {
+		return schema.LoadValidated(source)
+	}
+	if source != LiveSource {
+		err := result.Load(source)
+		if err != nil {
+			return result, fmt.Errorf("cannot load result %s: %s", source, err)
+		}
+		return result, nil
+	}
+	names := pluginNames
+	if len(names) == 0 {
+		names = q.Plugins()
+	}
+	if len(names) == 0 {
+		return result, fmt.Errorf("there are no extractors for %s", q.Pattern.Extractor)
+	}
+	plugins, err := p.SelectPlugins(names, settings.Exclude)
+	if err != nil {
+		return result, err
+	}
+	raw, err := settings.PluginOptions(optionSpecs)
+	if err != nil {
+		return result, err
+	}
+	err = plugins.SetOptions(raw)
+	if err != nil {
+		return result, err
+	}
+
+	// We want what we can get, so failure is allowed
+	options := p.ExtractOptions{AllowFail: true, Jobs: runtime.NumCPU()}
+	return plugins.Extract(context.Background(), options)
+}
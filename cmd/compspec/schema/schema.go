@@ -0,0 +1,54 @@
+package schema
+
+import (
+	"fmt"
+
+	"github.com/compspec/compspec-go/pkg/plugin"
+	"github.com/compspec/compspec-go/pkg/schema"
+)
+
+// Extractor prints the JSON Schema for the data of a named extractor
+func Extractor(name string) error {
+	if name == "" {
+		return fmt.Errorf("please provide the name of an extractor")
+	}
+	registration, err := plugin.Lookup(name)
+	if err != nil {
+		return err
+	}
+	if registration.Kind != plugin.ExtractorKind {
+		return fmt.Errorf("%s is not an extractor", name)
+	}
+	p, err := registration.New([]string{})
+	if err != nil {
+		return err
+	}
+	extractor, ok := p.(plugin.Extractor)
+	if !ok {
+		return fmt.Errorf("%s is not an extractor", name)
+	}
+	s, err := schema.ForExtractor(extractor)
+	if err != nil {
+		return err
+	}
+	b, err := s.ToJson()
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(b))
+	return nil
+}
+
+// Validate checks a saved extraction result against the schema of each
+// extractor in it
+func Validate(filename string) error {
+	if filename == "" {
+		return fmt.Errorf("please provide an extraction result to validate")
+	}
+	_, err := schema.LoadValidated(filename)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%s is valid\n", filename)
+	return nil
+}
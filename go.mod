@@ -6,8 +6,11 @@ require (
 	github.com/akamensky/argparse v1.4.0
 	github.com/converged-computing/jsongraph-go v0.0.0-20240229082022-c6887a5a00fe
 	github.com/converged-computing/nfd-source v0.0.0-20240224025007-20d686e64926
+	github.com/cyphar/filepath-securejoin v0.2.4
 	github.com/jedib0t/go-pretty/v6 v6.5.4
+	github.com/klauspost/compress v1.17.7
 	github.com/moby/moby v25.0.3+incompatible
+	github.com/opencontainers/go-digest v1.0.0
 	github.com/opencontainers/image-spec v1.1.0
 	github.com/scylladb/go-set v1.0.2
 	golang.org/x/sys v0.16.0
@@ -18,14 +21,12 @@ require (
 require (
 	github.com/containerd/log v0.1.0 // indirect
 	github.com/coreos/go-systemd/v22 v22.3.2 // indirect
-	github.com/cyphar/filepath-securejoin v0.2.4 // indirect
 	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/fsnotify/fsnotify v1.7.0 // indirect
 	github.com/godbus/dbus/v5 v5.0.6 // indirect
 	github.com/klauspost/cpuid/v2 v2.2.7 // indirect
 	github.com/mattn/go-runewidth v0.0.15 // indirect
 	github.com/moby/sys/mountinfo v0.5.0 // indirect
-	github.com/opencontainers/go-digest v1.0.0 // indirect
 	github.com/opencontainers/runc v1.1.12 // indirect
 	github.com/opencontainers/runtime-spec v1.0.3-0.20210326190908-1c3f411f0417 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
@@ -0,0 +1,341 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+
+	"github.com/compspec/compspec-go/pkg/plugin"
+	"github.com/compspec/compspec-go/pkg/redact"
+
+	"sigs.k8s.io/yaml"
+)
+
+// Config files are layered, and a later file takes precedence over an
+// earlier one: the system, the user, the project (the working directory),
+// and then a file from COMPSPEC_CONFIG or --config. A file that does not
+// exist is skipped, unless it was asked for. Environment variables
+// take precedence over files, and flags over all of them.
+const (
+	SystemConfig  = "/etc/compspec/config.yaml"
+	UserConfig    = "compspec/config.yaml"
+	ProjectConfig = ".compspec.yaml"
+
+	ConfigEnv    = "COMPSPEC_CONFIG"
+	ProfileEnv   = "COMPSPEC_PROFILE"
+	CacheEnv     = "COMPSPEC_CACHE"
+	MediaTypeEnv = "COMPSPEC_MEDIA_TYPE"
+)
+
+// A Config file has defaults for compspec, and named profiles, for example:
+//
+//	cache: /var/cache/compspec
+//	options:
+//	  library:
+//	    mpi.exec: /opt/mpi/bin/mpirun
+//	profiles:
+//	  rainbow-node:
+//	    description: extraction for a rainbow cluster node
+//	    names:
+//	    - library
+//	    - nfd[cpu,memory,network,storage,system]
+//	    - system[cpu,processor,arch,memory]
+type Config struct {
+	Settings
+
+	// Named profiles, selected with --profile
+	Profiles map[string]Profile `json:"profiles,omitempty"`
+
+	// The files that were loaded, in order
+	Files []string `json:"-"`
+}
+
+// A Profile is a named set of settings, used on top of the defaults
+type Profile struct {
+	Description string `json:"description,omitempty"`
+	Settings
+}
+
+// Settings are what a config (or a profile in it) can set
+type Settings struct {
+
+	// Plugins (and sections) to extract, and what to leave out
+	Names   []string `json:"names,omitempty"`
+	Exclude []string `json:"exclude,omitempty"`
+
+	// Where artifacts are cached, and the media type to pull them with
+	Cache     string `json:"cache,omitempty"`
+	MediaType string `json:"mediaType,omitempty"`
+
+	// Schema urls by name, used instead of those in an artifact request
+	Schemas map[string]string `json:"schemas,omitempty"`
+
+	// Redaction rules, used after any on the command line
+	Redact []redact.Rule `json:"redact,omitempty"`
+
+	// Options for plugins, by plugin and then option name
+	Options map[string]map[string]interface{} `json:"options,omitempty"`
+}
+
+// Load reads a config file
+func Load(filename string) (Config, error) {
+	config := Config{}
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return config, err
+	}
+	err = yaml.UnmarshalStrict(content, &config)
+	if err != nil {
+		return config, fmt.Errorf("%s is not a valid config file: %s", filename, err)
+	}
+	err = config.Validate()
+	if err != nil {
+		return config, fmt.Errorf("%s is not a valid config file: %s", filename, err)
+	}
+	config.Files = []string{filename}
+	return config, nil
+}
+
+// Paths returns the config files that we look for, in order
+func Paths() []string {
+	paths := []string{SystemConfig}
+	dir, err := os.UserConfigDir()
+	if err == nil {
+		paths = append(paths, filepath.Join(dir, UserConfig))
+	}
+	return append(paths, ProjectConfig)
+}
+
+// LoadLayers loads and merges the config files that exist, then a named
+// file (from --config or COMPSPEC_CONFIG) which must exist, and then
+// settings from environment variables
+func LoadLayers(filename string) (Config, error) {
+	config := Config{}
+	for _, path := range Paths() {
+		if _, err := os.Stat(path); err != nil {
+			continue
+		}
+		layer, err := Load(path)
+		if err != nil {
+			return config, err
+		}
+		config.Merge(layer)
+	}
+	if filename == "" {
+		filename = os.Getenv(ConfigEnv)
+	}
+	if filename != "" {
+		layer, err := Load(filename)
+		if err != nil {
+			return config, err
+		}
+		config.Merge(layer)
+	}
+	if cache := os.Getenv(CacheEnv); cache != "" {
+		config.Cache = cache
+	}
+	if mediaType := os.Getenv(MediaTypeEnv); mediaType != "" {
+		config.MediaType = mediaType
+	}
+	return config, nil
+}
+
+// Resolve loads the config layers and returns the settings for a profile,
+// which can be empty for the defaults, or come from COMPSPEC_PROFILE
+func Resolve(filename, profile string) (Settings, error) {
+	config, err := LoadLayers(filename)
+	if err != nil {
+		return Settings{}, err
+	}
+	return config.Profile(profile)
+}
+
+// Merge adds a layer on top of the config. A profile in the layer
+// replaces a profile of the same name.
+func (c *Config) Merge(layer Config) {
+	c.Settings.Merge(layer.Settings)
+	for name, profile := range layer.Profiles {
+		if c.Profiles == nil {
+			c.Profiles = map[string]Profile{}
+		}
+		c.Profiles[name] = profile
+	}
+	c.Files = append(c.Files, layer.Files...)
+}
+
+// Profile returns the settings for a named profile on top of the defaults
+func (c Config) Profile(name string) (Settings, error) {
+	settings := c.Settings.Copy()
+	if name == "" {
+		name = os.Getenv(ProfileEnv)
+	}
+	if name == "" {
+		return settings, nil
+	}
+	profile, ok := c.Profiles[name]
+	if !ok {
+		return settings, fmt.Errorf("profile %s is not known, known profiles are %s", name, c.ProfileNames())
+	}
+	settings.Merge(profile.Settings)
+	return settings, nil
+}
+
+// ProfileNames returns the sorted names of profiles
+func (c Config) ProfileNames() []string {
+	names := []string{}
+	for name := range c.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Validate ensures that redaction rules in the config are valid
+func (c Config) Validate() error {
+	err := c.Settings.Validate()
+	if err != nil {
+		return err
+	}
+	for _, name := range c.ProfileNames() {
+		err := c.Profiles[name].Settings.Validate()
+		if err != nil {
+			return fmt.Errorf("profile %s: %s", name, err)
+		}
+	}
+	return nil
+}
+
+// Validate ensures that redaction rules are valid
+func (s Settings) Validate() error {
+	for _, rule := range s.Redact {
+		err := rule.Validate()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Merge adds settings on top of these. Names and a cache or media type
+// are replaced, the rules of a later layer are used first, and schemas
+// and options are merged by name.
+func (s *Settings) Merge(layer Settings) {
+	if len(layer.Names) > 0 {
+		s.Names = layer.Names
+	}
+	if len(layer.Exclude) > 0 {
+		s.Exclude = layer.Exclude
+	}
+	if layer.Cache != "" {
+		s.Cache = layer.Cache
+	}
+	if layer.MediaType != "" {
+		s.MediaType = layer.MediaType
+	}
+	for name, url := range layer.Schemas {
+		if s.Schemas == nil {
+			s.Schemas = map[string]string{}
+		}
+		s.Schemas[name] = url
+	}
+	s.Redact = append(append([]redact.Rule{}, layer.Redact...), s.Redact...)
+	for name, options := range layer.Options {
+		if s.Options == nil {
+			s.Options = map[string]map[string]interface{}{}
+		}
+		if _, ok := s.Options[name]; !ok {
+			s.Options[name] = map[string]interface{}{}
+		}
+		for key, value := range options {
+			s.Options[name][key] = value
+		}
+	}
+}
+
+// Copy returns settings that can be merged without changing these
+func (s Settings) Copy() Settings {
+	settings := Settings{}
+	settings.Merge(s)
+	return settings
+}
+
+// PluginOptions returns raw option values by plugin, where options on the
+// command line (<plugin>.<option>=<value>) take precedence over settings
+func (s Settings) PluginOptions(specs []string) (map[string]map[string]string, error) {
+	overrides, err := plugin.ParseOptionSpecs(specs)
+	if err != nil {
+		return nil, err
+	}
+	raw := map[string]map[string]string{}
+	for name, options := range s.Options {
+		raw[name] = map[string]string{}
+		for key, value := range options {
+			raw[name][key] = stringify(value)
+		}
+	}
+	for name, options := range overrides {
+		if _, ok := raw[name]; !ok {
+			raw[name] = map[string]string{}
+		}
+		for key, value := range options {
+			raw[name][key] = value
+		}
+	}
+	return raw, nil
+}
+
+// OptionSpecs returns the plugin options as <plugin>.<option>=<value>,
+// sorted, for a plugin that is given options as a list
+func (s Settings) OptionSpecs() []string {
+	specs := []string{}
+	for name, options := range s.Options {
+		for key, value := range options {
+			specs = append(specs, fmt.Sprintf("%s.%s=%s", name, key, stringify(value)))
+		}
+	}
+	sort.Strings(specs)
+	return specs
+}
+
+// SchemaSpecs returns the schemas as <name>=<url>, sorted
+func (s Settings) SchemaSpecs() []string {
+	specs := []string{}
+	for name, url := range s.Schemas {
+		specs = append(specs, fmt.Sprintf("%s=%s", name, url))
+	}
+	sort.Strings(specs)
+	return specs
+}
+
+// RedactSpecs returns the redaction rules as <field>=<action>, in order
+// (the first rule that matches a field is used)
+func (s Settings) RedactSpecs() []string {
+	specs := []string{}
+	for _, rule := range s.Redact {
+		specs = append(specs, fmt.Sprintf("%s=%s", rule.Field, rule.Action))
+	}
+	return specs
+}
+
+// stringify returns a value from yaml as text, so it can be parsed as the
+// type of the option. A list is space separated, like a list value.
+func stringify(value interface{}) string {
+	switch value := value.(type) {
+	case nil:
+		return ""
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	case []interface{}:
+		items := ""
+		for i, item := range value {
+			if i > 0 {
+				items += " "
+			}
+			items += stringify(item)
+		}
+		return items
+	}
+	return fmt.Sprint(value)
+}
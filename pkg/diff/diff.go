@@ -0,0 +1,214 @@
+package diff
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/compspec/compspec-go/pkg/plugin"
+	"github.com/compspec/compspec-go/pkg/schema"
+	"github.com/compspec/compspec-go/pkg/types"
+)
+
+// The kinds of files that can be compared
+const (
+	ResultKind   = "result"
+	ArtifactKind = "artifact"
+)
+
+// A ChangeKind says how a field changed
+type ChangeKind string
+
+const (
+	Added   ChangeKind = "added"
+	Removed ChangeKind = "removed"
+	Changed ChangeKind = "changed"
+)
+
+// A Change is one field that is different. The old value is missing for
+// an added field, and the new value is missing for a removed one.
+type Change struct {
+	Field string        `json:"field"`
+	Kind  ChangeKind    `json:"kind"`
+	Old   *plugin.Value `json:"old,omitempty"`
+	New   *plugin.Value `json:"new,omitempty"`
+}
+
+// A Report has the changes from an old file to a new one, by field
+type Report struct {
+	Kind    string   `json:"kind"`
+	Old     string   `json:"old"`
+	New     string   `json:"new"`
+	Changes []Change `json:"changes"`
+}
+
+// HasChanges determines if the files are different
+func (r *Report) HasChanges() bool {
+	return len(r.Changes) > 0
+}
+
+// Files compares two extraction results, or two compatibility artifacts,
+// and fields that match an ignore pattern are not compared. Results can
+// be checked against the schema of each extractor first.
+func Files(oldPath, newPath string, ignore []string, validate bool) (Report, error) {
+	report := Report{Old: oldPath, New: newPath, Changes: []Change{}}
+	for _, pattern := range ignore {
+		_, err := path.Match(pattern, "")
+		if err != nil {
+			return report, fmt.Errorf("ignore pattern %s is not valid: %s", pattern, err)
+		}
+	}
+	oldKind, oldFields, err := Load(oldPath, validate)
+	if err != nil {
+		return report, err
+	}
+	newKind, newFields, err := Load(newPath, validate)
+	if err != nil {
+		return report, err
+	}
+	if oldKind != newKind {
+		return report, fmt.Errorf("%s (%s) and %s (%s) cannot be compared", oldPath, oldKind, newPath, newKind)
+	}
+	report.Kind = oldKind
+	report.Changes = Compare(oldFields, newFields, ignore)
+	return report, nil
+}
+
+// Load reads an extraction result or compatibility artifact, and returns
+// the kind of file and the flattened fields. A result is checked against
+// the schema of each extractor if we validate.
+func Load(filename string, validate bool) (string, map[string]plugin.Value, error) {
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return "", nil, err
+	}
+
+	// We look at the top level keys to know what we have
+	keys := map[string]json.RawMessage{}
+	err = json.Unmarshal(content, &keys)
+	if err != nil {
+		return "", nil, fmt.Errorf("%s is not valid json: %s", filename, err)
+	}
+	if _, ok := keys["results"]; ok {
+		if validate {
+			err := schema.ValidateJSON(content)
+			if err != nil {
+				return "", nil, fmt.Errorf("%s is not valid: %s", filename, err)
+			}
+		}
+		result := plugin.Result{}
+		err := json.Unmarshal(content, &result)
+		if err != nil {
+			return "", nil, fmt.Errorf("%s is not a valid extraction result: %s", filename, err)
+		}
+		result.SetTypes()
+		return ResultKind, result.Flatten(), nil
+	}
+	if _, ok := keys["compatibilities"]; ok {
+		artifact := types.CompatibilityRequest{}
+		err := json.Unmarshal(content, &artifact)
+		if err != nil {
+			return "", nil, fmt.Errorf("%s is not a valid compatibility artifact: %s", filename, err)
+		}
+		return ArtifactKind, flattenArtifact(artifact), nil
+	}
+	return "", nil, fmt.Errorf("%s is not an extraction result or compatibility artifact", filename)
+}
+
+// flattenArtifact returns the attributes of an artifact by <name>.<attribute>
+func flattenArtifact(artifact types.CompatibilityRequest) map[string]plugin.Value {
+	fields := map[string]plugin.Value{}
+	for _, compat := range artifact.Compatibilities {
+		if compat.Version != "" {
+			fields[compat.Name+".version"] = plugin.String(compat.Version)
+		}
+		for key, value := range compat.Attributes {
+			fields[compat.Name+"."+key] = plugin.String(value)
+		}
+	}
+	return fields
+}
+
+// Compare returns the changes from old to new fields, sorted by field.
+// Values of different types (e.g., a string and a number) are compared
+// as the same type when they can be.
+func Compare(old, new map[string]plugin.Value, ignore []string) []Change {
+	changes := []Change{}
+	for field, oldValue := range old {
+		if Ignored(field, ignore) {
+			continue
+		}
+		oldValue := oldValue
+		newValue, ok := new[field]
+		if !ok {
+			changes = append(changes, Change{Field: field, Kind: Removed, Old: &oldValue})
+			continue
+		}
+		if oldValue.Compare(newValue) != 0 {
+			changes = append(changes, Change{Field: field, Kind: Changed, Old: &oldValue, New: &newValue})
+		}
+	}
+	for field, newValue := range new {
+		if _, ok := old[field]; ok || Ignored(field, ignore) {
+			continue
+		}
+		newValue := newValue
+		changes = append(changes, Change{Field: field, Kind: Added, New: &newValue})
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Field < changes[j].Field
+	})
+	return changes
+}
+
+// Ignored determines if a field matches an ignore pattern. A pattern is
+// a glob (e.g., system.processor.*) for the entire field, or for just
+// the last part of it (e.g., cpu_mhz) so it matches in every section.
+func Ignored(field string, patterns []string) bool {
+	last := field[strings.LastIndex(field, ".")+1:]
+	for _, pattern := range patterns {
+		if plugin.MatchGlob(pattern, field) || plugin.MatchGlob(pattern, last) {
+			return true
+		}
+	}
+	return false
+}
+
+// Print prints the changes for a person to read
+func (r *Report) Print() {
+	if !r.HasChanges() {
+		fmt.Printf("There are no changes from %s to %s\n", r.Old, r.New)
+		return
+	}
+	fmt.Printf("--- %s\n+++ %s\n", r.Old, r.New)
+	for _, change := range r.Changes {
+		switch change.Kind {
+		case Added:
+			fmt.Printf("+ %s: %s\n", change.Field, show(change.New))
+		case Removed:
+			fmt.Printf("- %s: %s\n", change.Field, show(change.Old))
+		case Changed:
+			fmt.Printf("~ %s: %s -> %s\n", change.Field, show(change.Old), show(change.New))
+		}
+	}
+	fmt.Printf("%d changed field(s)\n", len(r.Changes))
+}
+
+// show returns a value to print, quoted if it has whitespace that
+// would otherwise be hard to see (e.g., a newline)
+func show(value *plugin.Value) string {
+	text := value.String()
+	if strings.TrimSpace(text) != text || strings.ContainsAny(text, "\n\t") {
+		return strconv.Quote(text)
+	}
+	return text
+}
+
+// ToJson serializes the report to json
+func (r *Report) ToJson() ([]byte, error) {
+	return json.MarshalIndent(r, "", "  ")
+}
@@ -80,7 +80,23 @@ func (c *ClusterGraph) AddNode(
 	unit string,
 	path string,
 ) *graph.Node {
-	node := c.getNode(resource, name, size, exclusive, unit, path)
+	node := c.getNode(resource, name, "", size, exclusive, unit, path)
+	c.Graph.Nodes[*node.Label] = *node
+	return node
+}
+
+// AddNamedNode adds a node to the graph with a name (e.g., a hostname)
+// instead of the basename and a count (e.g., node0)
+func (c *ClusterGraph) AddNamedNode(
+	resource string,
+	basename string,
+	name string,
+	size int32,
+	exclusive bool,
+	unit string,
+	path string,
+) *graph.Node {
+	node := c.getNode(resource, basename, name, size, exclusive, unit, path)
 	c.Graph.Nodes[*node.Label] = *node
 	return node
 }
@@ -92,9 +108,11 @@ func (c *ClusterGraph) AddEdge(source, dest graph.Node, relation string) {
 }
 
 // getNode is a private shared function that can also be used to generate the root!
+// An empty fullname is the name with the count (e.g., node0).
 func (c *ClusterGraph) getNode(
 	resource string,
 	name string,
+	fullname string,
 	size int32,
 	exclusive bool,
 	unit string,
@@ -113,6 +131,9 @@ func (c *ClusterGraph) getNode(
 	// The id in the metadata is the counter for that resource type
 	resourceCounter := fmt.Sprintf("%d", counter)
 	nameWithCount := fmt.Sprintf("%s%d", name, counter)
+	if fullname != "" {
+		nameWithCount = fullname
+	}
 
 	// The resource name is the type + the resource counter
 	// path should be assembled from parents up to this node
@@ -6,6 +6,7 @@ import (
 	"os"
 	"strings"
 
+	"github.com/compspec/compspec-go/pkg/plugin"
 	"github.com/compspec/compspec-go/pkg/utils"
 	"github.com/converged-computing/jsongraph-go/jsongraph/v2/graph"
 	jgf "github.com/converged-computing/jsongraph-go/jsongraph/v2/graph"
@@ -162,6 +163,9 @@ func (c *CompatibilityGraph) PrintMapping() error {
 // 3. If it exists, keep the set of images
 // 4. Continue to get sets of images for all desired features
 // 5. The intersection across those are the matches!
+// A field can also use a comparison (e.g., mpi.version>=4.1) in which case
+// we compare against every value for the key, and the value types are
+// inferred, so versions, numbers, and sizes (e.g., 16 GB) compare correctly.
 func (c *CompatibilityGraph) Match(fields []string) ([]string, error) {
 
 	// No fields, all are matches!
@@ -180,16 +184,13 @@ func (c *CompatibilityGraph) Match(fields []string) ([]string, error) {
 	started := false
 
 	for _, field := range fields {
-		if !strings.Contains(field, "=") {
-			fmt.Printf("Field request %s is missing '=', skipping", field)
+		key, operator, value, err := plugin.ParseComparison(field)
+		if err != nil {
+			return []string{}, err
 		}
-		parts := strings.SplitN(field, "=", 2)
-		key := parts[0]
-		value := parts[1]
-		nodeId := fmt.Sprintf("%s.%s", key, value)
 
 		// Do we have the node images
-		uris, ok := c.NodeLabels[nodeId]
+		uris, ok := c.lookup(key, operator, value)
 		fmt.Println(uris)
 		if !ok {
 			return []string{}, fmt.Errorf("Field %s is not known and cannot be matched.", field)
@@ -224,6 +225,38 @@ func (c *CompatibilityGraph) Match(fields []string) ([]string, error) {
 	return matches.List(), nil
 }
 
+// lookup returns the images for a key and value that satisfy the comparison.
+// An exact match (=) is a direct lookup, and otherwise we compare against
+// every value for the key. A value node is <key>.<value>, so we cannot tell
+// a nested key from a value with a dot, and assume the rest is the value.
+func (c *CompatibilityGraph) lookup(key, operator, value string) (map[string]bool, bool) {
+	if operator == "=" {
+		uris, ok := c.NodeLabels[fmt.Sprintf("%s.%s", key, value)]
+		if ok {
+			return uris, ok
+		}
+	}
+
+	// Compare typed values, so 4.10 is greater than 4.9
+	wanted := plugin.InferValue(value)
+	prefix := key + "."
+	found := false
+	uris := map[string]bool{}
+	for label, images := range c.NodeLabels {
+		if !strings.HasPrefix(label, prefix) {
+			continue
+		}
+		found = true
+		if !plugin.InferValue(strings.TrimPrefix(label, prefix)).Satisfies(operator, wanted) {
+			continue
+		}
+		for uri := range images {
+			uris[uri] = true
+		}
+	}
+	return uris, found
+}
+
 // Load graph from a cached file.
 // This assumes you know what you are doing, meaning
 // the schemas are not changing
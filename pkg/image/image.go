@@ -0,0 +1,333 @@
+package image
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/compspec/compspec-go/pkg/rootfs"
+	securejoin "github.com/cyphar/filepath-securejoin"
+	"github.com/klauspost/compress/zstd"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+const (
+	// A whiteout file removes a file from a lower layer, and an opaque
+	// whiteout removes everything in the directory from lower layers
+	whiteoutPrefix = ".wh."
+	whiteoutOpaque = ".wh..wh..opq"
+)
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// An Image is unpacked into a temporary directory
+type Image struct {
+
+	// The unpacked filesystem of the image
+	Rootfs string
+
+	// The image config (e.g., the platform)
+	Config ocispec.Image
+
+	// The temporary directory with everything
+	dir string
+}
+
+// Unpack unpacks an image in an OCI image layout (a directory or tarball)
+// or a docker save archive into a temporary root filesystem. Call Cleanup
+// to remove it when you are done.
+func Unpack(imagePath string, platform Platform) (*Image, error) {
+	dir, err := os.MkdirTemp("", "compspec-image-")
+	if err != nil {
+		return nil, err
+	}
+	image := Image{dir: dir, Rootfs: filepath.Join(dir, "rootfs")}
+	err = image.unpack(imagePath, platform)
+	if err != nil {
+		image.Cleanup()
+		return nil, err
+	}
+	return &image, nil
+}
+
+// unpack finds the layers for the platform and applies them in order
+func (i *Image) unpack(imagePath string, platform Platform) error {
+	info, err := os.Stat(imagePath)
+	if err != nil {
+		return err
+	}
+
+	// A tarball is extracted first, so we can find blobs by path
+	layoutPath := imagePath
+	if !info.IsDir() {
+		layoutPath = filepath.Join(i.dir, "layout")
+		f, err := os.Open(imagePath)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		archive, err := decompress(f)
+		if err != nil {
+			return err
+		}
+		err = extractArchive(archive, layoutPath)
+		if err != nil {
+			return fmt.Errorf("cannot extract %s: %s", imagePath, err)
+		}
+	}
+
+	layers, config, err := layout{path: layoutPath}.layers(platform)
+	if err != nil {
+		return err
+	}
+	i.Config = config
+
+	err = os.MkdirAll(i.Rootfs, 0755)
+	if err != nil {
+		return err
+	}
+	for _, layer := range layers {
+		err := applyLayer(layer, i.Rootfs)
+		if err != nil {
+			return fmt.Errorf("cannot apply layer %s: %s", filepath.Base(layer), err)
+		}
+	}
+	return nil
+}
+
+// Platform returns the platform of the image from the config
+func (i *Image) Platform() Platform {
+	return Platform{OS: i.Config.OS, Architecture: i.Config.Architecture, Variant: i.Config.Variant}
+}
+
+// Cleanup removes the unpacked image
+func (i *Image) Cleanup() error {
+	return os.RemoveAll(i.dir)
+}
+
+// decompress detects gzip or zstd compression, and otherwise the content
+// is assumed to be an uncompressed tar
+func decompress(content io.Reader) (io.Reader, error) {
+	reader := bufio.NewReader(content)
+	magic, err := reader.Peek(4)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	switch {
+	case bytes.HasPrefix(magic, gzipMagic):
+		return gzip.NewReader(reader)
+	case bytes.HasPrefix(magic, zstdMagic):
+		decoder, err := zstd.NewReader(reader)
+		if err != nil {
+			return nil, err
+		}
+		return decoder.IOReadCloser(), nil
+	}
+	return reader, nil
+}
+
+// applyLayer applies a layer tarball on top of a root filesystem
+func applyLayer(layer string, rootfsPath string) error {
+	f, err := os.Open(layer)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	content, err := decompress(f)
+	if err != nil {
+		return err
+	}
+
+	// Paths added by this layer, which an opaque whiteout does not remove
+	added := map[string]bool{}
+
+	reader := tar.NewReader(content)
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		// Paths are relative to the root, and we don't follow a link for
+		// the last component (we replace it)
+		name := path.Clean("/" + header.Name)
+		if name == "/" {
+			continue
+		}
+		parent, err := securejoin.SecureJoin(rootfsPath, path.Dir(name))
+		if err != nil {
+			return err
+		}
+		base := path.Base(name)
+		target := filepath.Join(parent, base)
+
+		// Whiteouts remove content from lower layers
+		if base == whiteoutOpaque {
+			err = removeChildren(parent, added)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		if strings.HasPrefix(base, whiteoutPrefix) {
+			removed, err := whiteoutTarget(rootfsPath, parent, strings.TrimPrefix(base, whiteoutPrefix))
+			if err != nil {
+				return fmt.Errorf("%s: %s", name, err)
+			}
+			err = os.RemoveAll(removed)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		err = os.MkdirAll(parent, 0755)
+		if err != nil {
+			return err
+		}
+		err = applyEntry(header, reader, rootfsPath, target)
+		if err != nil {
+			return fmt.Errorf("%s: %s", name, err)
+		}
+		added[target] = true
+	}
+}
+
+// whiteoutTarget returns the path that a whiteout removes. A hostile layer
+// could name . or .. (e.g., .wh..) to remove a directory above it, so the
+// name must be a single entry, and the path must be under the root.
+func whiteoutTarget(rootfsPath, parent, name string) (string, error) {
+	if name == "" || name == "." || name == ".." || strings.ContainsAny(name, `/\`) {
+		return "", fmt.Errorf("whiteout for %q is not valid", name)
+	}
+	target := filepath.Join(parent, name)
+	relative, err := filepath.Rel(rootfsPath, target)
+	if err != nil || relative == "." || relative == ".." || strings.HasPrefix(relative, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("whiteout for %q is outside of the root", name)
+	}
+	return target, nil
+}
+
+// applyEntry writes a single entry from a layer. We only need what an
+// extractor might read, so devices are skipped, and ownership is not kept.
+func applyEntry(header *tar.Header, content io.Reader, rootfsPath, target string) error {
+
+	// A directory is kept (and merged) unless something else replaces it
+	existing, err := os.Lstat(target)
+	if err == nil && !(existing.IsDir() && header.Typeflag == tar.TypeDir) {
+		err = os.RemoveAll(target)
+		if err != nil {
+			return err
+		}
+	}
+
+	// We keep the directory writable by us so it can be cleaned up
+	mode := os.FileMode(header.Mode).Perm()
+	switch header.Typeflag {
+	case tar.TypeDir:
+		err := os.MkdirAll(target, mode|0700)
+		if err != nil {
+			return err
+		}
+		return os.Chmod(target, mode|0700)
+
+	case tar.TypeReg:
+		return writeFile(target, content, mode|0400)
+
+	case tar.TypeSymlink:
+		return os.Symlink(header.Linkname, target)
+
+	case tar.TypeLink:
+		source, err := securejoin.SecureJoin(rootfsPath, header.Linkname)
+		if err != nil {
+			return err
+		}
+		err = os.Link(source, target)
+		if err != nil {
+			return copyFile(source, target)
+		}
+	}
+	return nil
+}
+
+// copyFile copies a file, for a hard link we cannot make
+func copyFile(source, target string) error {
+	f, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	return writeFile(target, f, info.Mode().Perm()|0400)
+}
+
+// removeChildren removes everything in a directory that was not added
+// by the current layer
+func removeChildren(dir string, added map[string]bool) error {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		path := filepath.Join(dir, entry.Name())
+		if added[path] {
+			continue
+		}
+		err := os.RemoveAll(path)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// OpenRoot returns the root to extract from, which is an unpacked image,
+// a directory, or the host (when both are empty). The cleanup function
+// removes an unpacked image.
+func OpenRoot(rootPath, imagePath, platform string) (rootfs.Root, func() error, error) {
+	cleanup := func() error { return nil }
+	if imagePath == "" {
+		if platform != "" {
+			return rootfs.Root{}, cleanup, fmt.Errorf("a platform can only be used with an image")
+		}
+		root, err := rootfs.New(rootPath)
+		return root, cleanup, err
+	}
+	if rootPath != "" {
+		return rootfs.Root{}, cleanup, fmt.Errorf("only one of a root or an image can be used")
+	}
+	selected, err := ParsePlatform(platform)
+	if err != nil {
+		return rootfs.Root{}, cleanup, err
+	}
+	image, err := Unpack(imagePath, selected)
+	if err != nil {
+		return rootfs.Root{}, cleanup, err
+	}
+	fmt.Fprintf(os.Stderr, "Unpacked image %s for %s\n", imagePath, image.Platform())
+	root, err := rootfs.NewImage(image.Rootfs)
+	if err != nil {
+		image.Cleanup()
+		return root, cleanup, err
+	}
+	return root, image.Cleanup, nil
+}
@@ -0,0 +1,258 @@
+package image
+
+import (
+	"archive/tar"
+	_ "crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	securejoin "github.com/cyphar/filepath-securejoin"
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+const (
+	// Docker media types that are the same as their OCI counterparts
+	dockerManifestList = "application/vnd.docker.distribution.manifest.list.v2+json"
+	dockerManifest     = "application/vnd.docker.distribution.manifest.v2+json"
+
+	// The manifest written by docker save
+	dockerArchiveManifest = "manifest.json"
+)
+
+// A Platform selects an image from an index, e.g., linux/arm64/v8
+type Platform struct {
+	OS           string
+	Architecture string
+	Variant      string
+
+	// A platform that was asked for must match, and otherwise (for the
+	// platform we are on) an image with one platform is used as is
+	required bool
+}
+
+// ParsePlatform parses os/arch[/variant]. Empty is the platform we are on.
+func ParsePlatform(value string) (Platform, error) {
+	if value == "" {
+		return Platform{OS: "linux", Architecture: runtime.GOARCH}, nil
+	}
+	parts := strings.Split(value, "/")
+	if len(parts) < 2 || len(parts) > 3 || parts[0] == "" || parts[1] == "" {
+		return Platform{}, fmt.Errorf("platform %s is not in the format os/arch[/variant]", value)
+	}
+	platform := Platform{OS: parts[0], Architecture: parts[1], required: true}
+	if len(parts) == 3 {
+		platform.Variant = parts[2]
+	}
+	return platform, nil
+}
+
+// String returns the platform as os/arch[/variant]
+func (p Platform) String() string {
+	if p.Variant == "" {
+		return p.OS + "/" + p.Architecture
+	}
+	return p.OS + "/" + p.Architecture + "/" + p.Variant
+}
+
+// matches determines if a descriptor platform is the one we want
+func (p Platform) matches(platform *ocispec.Platform) bool {
+	if platform == nil {
+		return false
+	}
+	if platform.OS != p.OS || platform.Architecture != p.Architecture {
+		return false
+	}
+	return p.Variant == "" || platform.Variant == p.Variant
+}
+
+// layout is an OCI image layout (or docker save archive) in a directory
+type layout struct {
+	path string
+}
+
+// readJSON reads a JSON file in the layout
+func (l layout) readJSON(name string, v interface{}) error {
+	path, err := securejoin.SecureJoin(l.path, name)
+	if err != nil {
+		return err
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(content, v)
+}
+
+// blobPath returns the path to a blob in the layout by digest
+func (l layout) blobPath(d digest.Digest) (string, error) {
+	err := d.Validate()
+	if err != nil {
+		return "", err
+	}
+	return securejoin.SecureJoin(l.path, filepath.Join("blobs", d.Algorithm().String(), d.Encoded()))
+}
+
+// layers returns the paths to the layers of the image (lowest first),
+// and the image config, for the platform
+func (l layout) layers(platform Platform) ([]string, ocispec.Image, error) {
+	config := ocispec.Image{}
+
+	// An OCI layout has an index.json (docker save also writes one now)
+	_, err := os.Stat(filepath.Join(l.path, ocispec.ImageIndexFile))
+	if err == nil {
+		index := ocispec.Index{}
+		err := l.readJSON(ocispec.ImageIndexFile, &index)
+		if err != nil {
+			return nil, config, fmt.Errorf("cannot read %s: %s", ocispec.ImageIndexFile, err)
+		}
+		manifest, err := l.resolve(index, platform)
+		if err != nil {
+			return nil, config, err
+		}
+		err = l.readBlob(manifest.Config.Digest, &config)
+		if err != nil {
+			return nil, config, fmt.Errorf("cannot read image config: %s", err)
+		}
+		paths := []string{}
+		for _, layer := range manifest.Layers {
+			path, err := l.blobPath(layer.Digest)
+			if err != nil {
+				return nil, config, err
+			}
+			paths = append(paths, path)
+		}
+		return paths, config, nil
+	}
+
+	// Otherwise an older docker save archive lists layers by path
+	archive := []struct {
+		Config string
+		Layers []string
+	}{}
+	err = l.readJSON(dockerArchiveManifest, &archive)
+	if err != nil {
+		return nil, config, fmt.Errorf("%s is not an OCI image layout or docker archive", l.path)
+	}
+	if len(archive) != 1 {
+		return nil, config, fmt.Errorf("docker archive %s has %d images, and one is expected", l.path, len(archive))
+	}
+	err = l.readJSON(archive[0].Config, &config)
+	if err != nil {
+		return nil, config, fmt.Errorf("cannot read image config: %s", err)
+	}
+	paths := []string{}
+	for _, layer := range archive[0].Layers {
+		path, err := securejoin.SecureJoin(l.path, layer)
+		if err != nil {
+			return nil, config, err
+		}
+		paths = append(paths, path)
+	}
+	return paths, config, nil
+}
+
+// readBlob reads a JSON blob by digest
+func (l layout) readBlob(d digest.Digest, v interface{}) error {
+	path, err := l.blobPath(d)
+	if err != nil {
+		return err
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(content, v)
+}
+
+// resolve finds the manifest for a platform in an index, following
+// nested indexes. With one manifest and no platform, that's the one.
+func (l layout) resolve(index ocispec.Index, platform Platform) (ocispec.Manifest, error) {
+	manifest := ocispec.Manifest{}
+
+	var selected *ocispec.Descriptor
+	for i, descriptor := range index.Manifests {
+		if platform.matches(descriptor.Platform) {
+			selected = &index.Manifests[i]
+			break
+		}
+		if selected == nil && descriptor.Platform == nil {
+			selected = &index.Manifests[i]
+		}
+	}
+	if selected == nil && len(index.Manifests) == 1 && !platform.required {
+		selected = &index.Manifests[0]
+	}
+	if selected == nil {
+		return manifest, fmt.Errorf("there is no image for platform %s", platform)
+	}
+
+	switch selected.MediaType {
+	case ocispec.MediaTypeImageIndex, dockerManifestList:
+		nested := ocispec.Index{}
+		err := l.readBlob(selected.Digest, &nested)
+		if err != nil {
+			return manifest, fmt.Errorf("cannot read index %s: %s", selected.Digest, err)
+		}
+		return l.resolve(nested, platform)
+
+	case ocispec.MediaTypeImageManifest, dockerManifest:
+		err := l.readBlob(selected.Digest, &manifest)
+		if err != nil {
+			return manifest, fmt.Errorf("cannot read manifest %s: %s", selected.Digest, err)
+		}
+		return manifest, nil
+	}
+	return manifest, fmt.Errorf("%s is not a known manifest media type", selected.MediaType)
+}
+
+// extractArchive extracts a tar archive of a layout into a directory.
+// Only directories and regular files are needed.
+func extractArchive(archive io.Reader, dest string) error {
+	reader := tar.NewReader(archive)
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		path, err := securejoin.SecureJoin(dest, header.Name)
+		if err != nil {
+			return err
+		}
+		switch header.Typeflag {
+		case tar.TypeDir:
+			err = os.MkdirAll(path, 0755)
+		case tar.TypeReg:
+			err = writeFile(path, reader, 0644)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// writeFile writes content to a new file, creating the parent directory
+func writeFile(path string, content io.Reader, mode os.FileMode) error {
+	err := os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(f, content)
+	if err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,124 @@
+package plugin
+
+import (
+	"fmt"
+)
+
+// AnyType is for a field in a catalog that can have any type of value
+const AnyType ValueType = "any"
+
+// A FieldSpec describes a field (or a pattern of fields) that an extractor
+// returns for a section. The name can have a * for any part (e.g., a
+// processor number or kernel parameter), in the same way as a glob.
+// A field can match more than one spec, and must have the type of each,
+// so a catch-all (e.g., *) after more specific specs is usually AnyType.
+// An empty type is a string.
+type FieldSpec struct {
+	Section     string    `json:"section"`
+	Name        string    `json:"name"`
+	Type        ValueType `json:"type,omitempty"`
+	Unit        string    `json:"unit,omitempty"`
+	Description string    `json:"description,omitempty"`
+	Example     string    `json:"example,omitempty"`
+}
+
+// Matches determines if a field name (without the extractor and section)
+// is described by the spec
+func (f FieldSpec) Matches(name string) bool {
+	return MatchGlob(f.Name, name)
+}
+
+// Cataloged is an extractor that describes the fields it returns, so
+// they can be found without extracting (compspec list --fields)
+type Cataloged interface {
+	Fields() []FieldSpec
+}
+
+// A CatalogEntry is a field in the catalog for an extractor
+type CatalogEntry struct {
+	Field       string    `json:"field"`
+	Type        ValueType `json:"type"`
+	Unit        string    `json:"unit,omitempty"`
+	Description string    `json:"description,omitempty"`
+	Example     string    `json:"example,omitempty"`
+
+	// A field that describes the running system cannot be extracted from
+	// an image, and the rest can
+	HostOnly bool `json:"hostOnly"`
+}
+
+// Catalog returns the fields for the sections of an extractor, in the
+// order of the sections, and false if the extractor has no catalog
+func Catalog(e Extractor) ([]CatalogEntry, bool) {
+	entries := []CatalogEntry{}
+	cataloged, ok := e.(Cataloged)
+	if !ok {
+		return entries, false
+	}
+	hostOnly, isHostOnly := e.(HostOnly)
+	fields := cataloged.Fields()
+	if len(fields) == 0 {
+		return entries, false
+	}
+	for _, section := range e.Sections() {
+		for _, field := range fields {
+			if field.Section != section {
+				continue
+			}
+			entry := CatalogEntry{
+				Field:       fmt.Sprintf("%s.%s.%s", e.Name(), section, field.Name),
+				Type:        field.Type,
+				Unit:        field.Unit,
+				Description: field.Description,
+				Example:     field.Example,
+				HostOnly:    isHostOnly && hostOnly.HostOnly(section),
+			}
+			if entry.Type == "" {
+				entry.Type = StringType
+			}
+			entries = append(entries, entry)
+		}
+	}
+	return entries, true
+}
+
+// SetTypes parses string values as the type in the catalog of their
+// extractor, since values are saved as strings. A value that does not
+// parse (or an extractor without a catalog) stays a string.
+func (r *Result) SetTypes() {
+	for name, data := range r.Results {
+		p, err := Described(name)
+		if err != nil {
+			continue
+		}
+		cataloged, ok := p.(Cataloged)
+		if !ok {
+			continue
+		}
+		setTypes(cataloged.Fields(), data)
+	}
+}
+
+// setTypes parses the string values of extractor data as the type of the
+// first spec that describes the field
+func setTypes(fields []FieldSpec, data PluginData) {
+	for sectionName, section := range data.Sections {
+		for field, value := range section {
+			if value.Type() != StringType {
+				continue
+			}
+			for _, spec := range fields {
+				if spec.Section != sectionName || !spec.Matches(field) {
+					continue
+				}
+				if spec.Type != AnyType && spec.Type != "" && spec.Type != StringType {
+					parsed, err := ParseValue(value.String(), spec.Type)
+					if err == nil {
+						section[field] = parsed
+					}
+				}
+				break
+			}
+		}
+	}
+}
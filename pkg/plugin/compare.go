@@ -0,0 +1,52 @@
+package plugin
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Comparison operators, longest first so >= is found before >
+var Operators = []string{">=", "<=", "!=", ">", "<", "="}
+
+// ParseComparison splits a field into the key, operator, and value,
+// e.g., mpi.version>=4.1
+func ParseComparison(field string) (string, string, string, error) {
+	index := strings.IndexAny(field, "<>!=")
+	if index <= 0 {
+		return "", "", "", fmt.Errorf("Field request %s is missing a comparison (e.g., '=')", field)
+	}
+	operator, value, err := ParseOperator(field[index:])
+	if err != nil {
+		return "", "", "", fmt.Errorf("Field request %s has an unknown comparison", field)
+	}
+	return field[:index], operator, Unquote(value), nil
+}
+
+// ParseOperator splits a comparison without a key (e.g., >=4.1) into
+// the operator and value
+func ParseOperator(comparison string) (string, string, error) {
+	for _, operator := range Operators {
+		if strings.HasPrefix(comparison, operator) {
+			return operator, comparison[len(operator):], nil
+		}
+	}
+	return "", "", fmt.Errorf("%s does not start with a comparison (e.g., '=' or '>=')", comparison)
+}
+
+// Satisfies determines if a value satisfies a comparison with another
+func (v Value) Satisfies(operator string, other Value) bool {
+	result := v.Compare(other)
+	switch operator {
+	case ">=":
+		return result >= 0
+	case "<=":
+		return result <= 0
+	case ">":
+		return result > 0
+	case "<":
+		return result < 0
+	case "!=":
+		return result != 0
+	}
+	return result == 0
+}
@@ -2,9 +2,14 @@ package plugin
 
 import (
 	"fmt"
+	"path"
 	"strings"
 )
 
+// NameSeparators are characters that a plugin or section name can't have,
+// since they separate the parts of a field (or sections, in system[os,arch])
+const NameSeparators = ".[],"
+
 // Field holds (previously) flattened metadata for an <extractor>.<section>.<field>
 type Field struct {
 	Extractor string
@@ -29,3 +34,11 @@ func ParseField(field string) (Field, error) {
 	f.Field = strings.Join(parts[2:], ".")
 	return f, nil
 }
+
+// MatchGlob matches a name (e.g., a field) with a glob, where * matches
+// anything, including a dot. path.Match stops at a slash (e.g., in a path
+// value), so we replace it with something else.
+func MatchGlob(pattern, name string) bool {
+	matched, _ := path.Match(strings.ReplaceAll(pattern, "/", "\x00"), strings.ReplaceAll(name, "/", "\x00"))
+	return matched
+}
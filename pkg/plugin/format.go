@@ -0,0 +1,163 @@
+package plugin
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"sigs.k8s.io/yaml"
+)
+
+// Formats that a result can be written in
+const (
+	JsonFormat   = "json"
+	YamlFormat   = "yaml"
+	FlatFormat   = "flat"
+	EnvFormat    = "env"
+	NdjsonFormat = "ndjson"
+
+	// Environment variables for the env format start with this
+	EnvPrefix = "COMPSPEC_"
+)
+
+var (
+	Formats = []string{JsonFormat, YamlFormat, FlatFormat, EnvFormat, NdjsonFormat}
+
+	// Characters that are not allowed in an environment variable name
+	envInvalid = regexp.MustCompile("[^A-Z0-9_]+")
+)
+
+// Format serializes the result in a named format
+func (r *Result) Format(format string) ([]byte, error) {
+	switch format {
+	case JsonFormat, "":
+		return r.ToJson()
+	case YamlFormat:
+		return yaml.Marshal(r)
+	case FlatFormat:
+		return r.ToFlat(), nil
+	case EnvFormat:
+		return r.ToEnv()
+	case NdjsonFormat:
+		return r.ToNdjson()
+	}
+	return nil, fmt.Errorf("%s is not a known format, choices are %s", format, strings.Join(Formats, ", "))
+}
+
+// records returns every field in the result, sorted by name
+func (r *Result) records() []FieldRecord {
+	records := []FieldRecord{}
+	for extractor, data := range r.Results {
+		for section, fields := range data.Sections {
+			for field, value := range fields {
+				records = append(records, FieldRecord{Extractor: extractor, Section: section, Field: field, Value: value, Type: value.Type()})
+			}
+		}
+	}
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].Name() < records[j].Name()
+	})
+	return records
+}
+
+// ToFlat returns sorted <extractor>.<section>.<field>=<value> lines, which
+// can be used for custom fields (create artifact -a) or match fields. A
+// value that would not be read back as it is (e.g., with a newline, an
+// equals sign, or leading space) is quoted.
+func (r *Result) ToFlat() []byte {
+	var out bytes.Buffer
+	for _, record := range r.records() {
+		value := record.Value.String()
+		if needsQuote(value) {
+			value = strconv.Quote(value)
+		}
+		fmt.Fprintf(&out, "%s=%s\n", record.Name(), value)
+	}
+	return out.Bytes()
+}
+
+// needsQuote determines if a flat value must be quoted to be read back
+func needsQuote(value string) bool {
+	return strings.IndexFunc(value, unicode.IsControl) >= 0 ||
+		strings.Contains(value, "=") ||
+		strings.TrimSpace(value) != value ||
+		strings.HasPrefix(value, `"`)
+}
+
+// Unquote returns a value that was quoted by the flat format, or the
+// value as it is
+func Unquote(value string) string {
+	if !strings.HasPrefix(value, `"`) {
+		return value
+	}
+	unquoted, err := strconv.Unquote(value)
+	if err != nil {
+		return value
+	}
+	return unquoted
+}
+
+// ToEnv returns sorted shell exports, e.g., COMPSPEC_SYSTEM_OS_NAME='Rocky'
+// Values are single quoted so they are never expanded by the shell. Two
+// fields can have the same variable (e.g., system.os.name and
+// system.os_name), and then we can't export either.
+func (r *Result) ToEnv() ([]byte, error) {
+	var out bytes.Buffer
+	names := map[string]string{}
+	collisions := []string{}
+	for _, record := range r.records() {
+		name := EnvName(record.Name())
+		if other, ok := names[name]; ok {
+			collisions = append(collisions, fmt.Sprintf("%s and %s are both %s", other, record.Name(), name))
+			continue
+		}
+		names[name] = record.Name()
+		value := strings.ReplaceAll(record.Value.String(), "'", `'\''`)
+		fmt.Fprintf(&out, "export %s='%s'\n", name, value)
+	}
+	if len(collisions) > 0 {
+		return nil, fmt.Errorf("fields have the same environment variable, exclude one or use another format:\n  %s", strings.Join(collisions, "\n  "))
+	}
+	return out.Bytes(), nil
+}
+
+// EnvName returns the environment variable for a field. Characters that
+// are not allowed (e.g., a dot) become underscores.
+func EnvName(field string) string {
+	name := envInvalid.ReplaceAllString(strings.ToUpper(field), "_")
+	return EnvPrefix + strings.Trim(name, "_")
+}
+
+// A FieldRecord is a single field for newline delimited json, with the
+// type of the value (which is saved as a string)
+type FieldRecord struct {
+	Extractor string    `json:"extractor"`
+	Section   string    `json:"section"`
+	Field     string    `json:"field"`
+	Value     Value     `json:"value"`
+	Type      ValueType `json:"type"`
+}
+
+// Name returns the flattened <extractor>.<section>.<field>
+func (f FieldRecord) Name() string {
+	return fmt.Sprintf("%s.%s.%s", f.Extractor, f.Section, f.Field)
+}
+
+// ToNdjson returns one json object per field, sorted by field
+func (r *Result) ToNdjson() ([]byte, error) {
+	var out bytes.Buffer
+	for _, record := range r.records() {
+		b, err := json.Marshal(record)
+		if err != nil {
+			return nil, err
+		}
+		out.Write(b)
+		out.WriteByte('\n')
+	}
+	return out.Bytes(), nil
+}
@@ -0,0 +1,112 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// An Option is a setting that an extractor declares (e.g., the path to a
+// command it runs), so it can be changed on the command line or in a
+// config file. Options are named by section, e.g., mpi.exec.
+type Option struct {
+	Name        string
+	Type        ValueType
+	Default     string
+	Description string
+}
+
+// Options are typed values for declared options, by name
+type Options map[string]Value
+
+// ParseOptions parses and validates raw values for the options a plugin
+// declares, and fills in defaults for those that are not set
+func ParseOptions(name string, declared []Option, raw map[string]string) (Options, error) {
+	options := Options{}
+	known := map[string]Option{}
+	for _, option := range declared {
+		known[option.Name] = option
+		if option.Default == "" {
+			continue
+		}
+		value, err := ParseValue(option.Default, option.Type)
+		if err != nil {
+			return options, fmt.Errorf("default for option %s.%s is not valid: %s", name, option.Name, err)
+		}
+		options[option.Name] = value
+	}
+	for key, text := range raw {
+		option, ok := known[key]
+		if !ok {
+			return options, fmt.Errorf("option %s is not known for plugin %s, known options are %s", key, name, optionNames(declared))
+		}
+		value, err := ParseValue(text, option.Type)
+		if err != nil {
+			return options, fmt.Errorf("option %s.%s is not a valid %s: %s", name, key, option.Type, err)
+		}
+		options[key] = value
+	}
+	return options, nil
+}
+
+// optionNames returns the sorted names of options for messages
+func optionNames(declared []Option) []string {
+	names := []string{}
+	for _, option := range declared {
+		names = append(names, option.Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Get returns an option, if it is set
+func (o Options) Get(name string) (Value, bool) {
+	value, ok := o[name]
+	return value, ok
+}
+
+// String returns an option as a string, which is empty if it is not set
+func (o Options) String(name string) string {
+	value, ok := o[name]
+	if !ok {
+		return ""
+	}
+	return value.String()
+}
+
+// ParseOptionSpecs parses <plugin>.<option>=<value> into raw values by
+// plugin, e.g., library.mpi.exec=/opt/mpi/bin/mpirun
+func ParseOptionSpecs(specs []string) (map[string]map[string]string, error) {
+	raw := map[string]map[string]string{}
+	for _, spec := range specs {
+		key, value, found := strings.Cut(spec, "=")
+		name, option, hasOption := strings.Cut(key, ".")
+		if !found || !hasOption || name == "" || option == "" {
+			return raw, fmt.Errorf("option %s is not in the format <plugin>.<option>=<value>", spec)
+		}
+		if _, ok := raw[name]; !ok {
+			raw[name] = map[string]string{}
+		}
+		raw[name][option] = value
+	}
+	return raw, nil
+}
+
+// optionsKey is the context key for options
+type optionsKey struct{}
+
+// WithOptions returns a context that carries options for an extractor
+func WithOptions(ctx context.Context, options Options) context.Context {
+	return context.WithValue(ctx, optionsKey{}, options)
+}
+
+// OptionsFromContext returns the options from a context, which are
+// empty if not set
+func OptionsFromContext(ctx context.Context) Options {
+	options, ok := ctx.Value(optionsKey{}).(Options)
+	if !ok {
+		return Options{}
+	}
+	return options
+}
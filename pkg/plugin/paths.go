@@ -0,0 +1,24 @@
+package plugin
+
+import (
+	"os"
+	"path/filepath"
+)
+
+const (
+	// PluginPathEnv is a list of directories (separated like PATH) with
+	// plugins that are not compiled in (e.g., executables or definitions)
+	PluginPathEnv = "COMPSPEC_PLUGIN_PATH"
+)
+
+// PluginDirs returns directories to look for plugins in, the directories
+// provided first (e.g., on the command line) and then COMPSPEC_PLUGIN_PATH
+func PluginDirs(dirs []string) []string {
+	pluginDirs := []string{}
+	for _, dir := range append(dirs, filepath.SplitList(os.Getenv(PluginPathEnv))...) {
+		if dir != "" {
+			pluginDirs = append(pluginDirs, dir)
+		}
+	}
+	return pluginDirs
+}
@@ -1,32 +1,54 @@
 package plugin
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 )
 
-// A Plugin interface can define any of the following:
-//
-//	an Extract function to return extractor data across sections
-//	a validate function to typically check that the plugin is valid
-//	a Creation interface that can use extractor data to generate something new
-type PluginInterface interface {
+// A Plugin has a name and description. What it can do is determined by
+// the interfaces that it implements (e.g., Extractor, Creator, or both),
+// which are checked with a type assertion.
+type Plugin interface {
 	Name() string
 	Description() string
+}
 
-	// This is probably a dumb way to do it, but it works
-	IsExtractor() bool
-	IsCreator() bool
-
-	// Extractors
-	Extract(bool) (PluginData, error)
-	Validate() bool
+// An Extractor returns extractor data across sections. The context
+// can be cancelled or time out, and should be honored where possible.
+type Extractor interface {
+	Plugin
+	Extract(context.Context, bool) (PluginData, error)
 	Sections() []string
+}
 
-	// Creators take a map of named options
+// A Creator can use extractor data to generate something new
+// Creators take a map of named options
+type Creator interface {
+	Plugin
 	Create(PluginOptions) error
 }
 
+// A Validator can check that the plugin is valid, e.g., that requested
+// sections are known. This is an optional capability.
+type Validator interface {
+	Validate() bool
+}
+
+// A HostOnly extractor has sections that describe the running system
+// (e.g., the kernel or hardware) rather than files, so they cannot be
+// extracted from an image. This is an optional capability.
+type HostOnly interface {
+	HostOnly(section string) bool
+}
+
+// A Versioned plugin has its own version (e.g., an external extractor),
+// and otherwise it is the version of compspec it is compiled into.
+// This is an optional capability.
+type Versioned interface {
+	Version() string
+}
+
 // PluginOptions allow packaging named values of different types
 // This is an alternative to using interfaces.
 type PluginOptions struct {
@@ -39,9 +61,20 @@ type PluginOptions struct {
 // ExtractorData is returned by an extractor
 type PluginData struct {
 	Sections Sections `json:"sections,omitempty"`
+
+	// Status is how extraction went for each section
+	Status map[string]SectionStatus `json:"status,omitempty"`
 }
 type Sections map[string]PluginSection
 
+// SetStatus records the status for a section
+func (e *PluginData) SetStatus(name string, status SectionStatus) {
+	if e.Status == nil {
+		e.Status = map[string]SectionStatus{}
+	}
+	e.Status[name] = status
+}
+
 // Print extractor data to the console
 func (e *PluginData) Print() {
 	for name, section := range e.Sections {
@@ -51,6 +84,12 @@ func (e *PluginData) Print() {
 		}
 	}
 
+	// Only show the sections that did not go as planned
+	for name, status := range e.Status {
+		if status.State != StateOK {
+			fmt.Printf(" -- Section %s is %s: %s\n", name, status.State, status.Error)
+		}
+	}
 }
 
 // ToJson serializes to json
@@ -62,8 +101,9 @@ func (e *PluginData) ToJson() (string, error) {
 	return string(b), err
 }
 
-// An extractor section corresponds to a named group of attributes
-type PluginSection map[string]string
+// An extractor section corresponds to a named group of attributes, where
+// each attribute is a (typed) Value
+type PluginSection map[string]Value
 
 // Extractors is a lookup of registered extractors by name
-type Plugins map[string]PluginInterface
+type Plugins map[string]Plugin
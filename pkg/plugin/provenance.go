@@ -0,0 +1,60 @@
+package plugin
+
+import (
+	"time"
+
+	"github.com/compspec/compspec-go/pkg/types"
+)
+
+// Provenance describes where and how a result was extracted, so results
+// from many nodes (or runs) can be told apart
+type Provenance struct {
+
+	// The host that extraction ran on
+	Hostname  string `json:"hostname,omitempty"`
+	MachineID string `json:"machineId,omitempty"`
+	BootID    string `json:"bootId,omitempty"`
+
+	// When extraction started
+	Timestamp time.Time `json:"timestamp"`
+
+	// The version of compspec and the command line it was run with
+	Version string   `json:"version,omitempty"`
+	Command []string `json:"command,omitempty"`
+
+	// The plugins and sections asked for, e.g., system[os,arch]
+	Requested []string `json:"requested,omitempty"`
+
+	// The version of each extractor in the result
+	Plugins map[string]string `json:"plugins,omitempty"`
+
+	// The container runtime (e.g., docker or kubernetes) if extraction
+	// ran in a container, which means the host is only partly visible
+	Container string `json:"container,omitempty"`
+
+	// The root directory or image that was extracted from, if not the host
+	Root  string `json:"root,omitempty"`
+	Image string `json:"image,omitempty"`
+}
+
+// NodeName returns a name to identify the node a result is for, which is
+// the hostname, or the machine id if the hostname is not known
+func (p *Provenance) NodeName() string {
+	if p == nil {
+		return ""
+	}
+	if p.Hostname != "" {
+		return p.Hostname
+	}
+	return p.MachineID
+}
+
+// VersionOf returns the version of a plugin. A plugin that does not have
+// its own version is the version of compspec.
+func VersionOf(p Plugin) string {
+	versioned, ok := p.(Versioned)
+	if !ok {
+		return types.Version
+	}
+	return versioned.Version()
+}
@@ -0,0 +1,127 @@
+package plugin
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Kind describes what a registered plugin is able to do
+type Kind string
+
+const (
+	ExtractorKind Kind = "extractor"
+	CreatorKind   Kind = "creator"
+)
+
+// A Factory instantiates a plugin for a set of (optionally) requested sections.
+// Creators are free to ignore sections.
+type Factory func(sections []string) (Plugin, error)
+
+// A Registration is how a plugin package makes itself known to compspec.
+// It is typically done in an init function of the plugin package, e.g.,
+//
+//	func init() {
+//		plugin.Register(plugin.Registration{Name: "system", ...})
+//	}
+type Registration struct {
+	Name        string
+	Description string
+	Kind        Kind
+
+	// Sections are the valid sections for the plugin (extractors)
+	Sections []string
+
+	// Options the plugin can be given (extractors), see OptionsFromContext
+	Options []Option
+	New     Factory
+
+	// Cached returns the plugin (for all sections) if it can be without
+	// running anything, and is for plugins that run something to describe
+	// themselves (e.g., external extractors). Without it, New is used.
+	Cached func() (Plugin, bool)
+}
+
+var (
+	registryMutex sync.RWMutex
+	registry      = map[string]Registration{}
+)
+
+// Register adds a plugin to the registry. It panics if the registration
+// is missing a name or factory, or if the name is already registered,
+// in the same way that database/sql does for drivers.
+func Register(r Registration) {
+	registryMutex.Lock()
+	defer registryMutex.Unlock()
+
+	if r.Name == "" {
+		panic("plugin: Register called with an empty name")
+	}
+	if r.New == nil {
+		panic(fmt.Sprintf("plugin: Register called with a nil factory for %s", r.Name))
+	}
+	if _, exists := registry[r.Name]; exists {
+		panic(fmt.Sprintf("plugin: Register called twice for %s", r.Name))
+	}
+	registry[r.Name] = r
+}
+
+// Lookup returns the registration for a plugin by exact name
+func Lookup(name string) (Registration, error) {
+	registryMutex.RLock()
+	defer registryMutex.RUnlock()
+
+	r, ok := registry[name]
+	if !ok {
+		return r, fmt.Errorf("unknown plugin %q, known plugins are %s", name, registeredNames())
+	}
+	return r, nil
+}
+
+// Described returns a plugin (for all sections) to describe its fields,
+// e.g., to type values on load. A plugin that must run something to
+// describe itself is only described if it has been already.
+func Described(name string) (Plugin, error) {
+	r, err := Lookup(name)
+	if err != nil {
+		return nil, err
+	}
+	if r.Cached == nil {
+		return r.New([]string{})
+	}
+	p, ok := r.Cached()
+	if !ok {
+		return nil, fmt.Errorf("plugin %s has not been described", name)
+	}
+	return p, nil
+}
+
+// Registered returns all registrations, creators first and then extractors,
+// each sorted by name so the order is consistent between runs.
+func Registered() []Registration {
+	registryMutex.RLock()
+	defer registryMutex.RUnlock()
+
+	registrations := make([]Registration, 0, len(registry))
+	for _, r := range registry {
+		registrations = append(registrations, r)
+	}
+	sort.Slice(registrations, func(i, j int) bool {
+		if registrations[i].Kind != registrations[j].Kind {
+			return registrations[i].Kind == CreatorKind
+		}
+		return registrations[i].Name < registrations[j].Name
+	})
+	return registrations
+}
+
+// registeredNames returns a sorted list of registered names for messages.
+// The caller is expected to hold the lock.
+func registeredNames() []string {
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
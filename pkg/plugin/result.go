@@ -5,14 +5,30 @@ import (
 	"fmt"
 	"os"
 	"strings"
+	"time"
 )
 
 // A Result wraps named extractor data, just for easy dumping to json
 type Result struct {
 	Results map[string]PluginData `json:"results,omitempty"`
+
+	// Provenance is where and how the result was extracted
+	Provenance *Provenance `json:"provenance,omitempty"`
+
+	// Redacted fields, and what was done to them (e.g., dropped)
+	Redacted map[string]string `json:"redacted,omitempty"`
+}
+
+// SetRedacted records that a field was redacted
+func (r *Result) SetRedacted(field, action string) {
+	if r.Redacted == nil {
+		r.Redacted = map[string]string{}
+	}
+	r.Redacted[field] = action
 }
 
-// Load a filename into the result object!
+// Load a filename into the result object! Values are given the types
+// in the catalog of their extractor.
 func (r *Result) Load(filename string) error {
 
 	file, err := os.ReadFile(filename)
@@ -23,6 +39,7 @@ func (r *Result) Load(filename string) error {
 	if err != nil {
 		return err
 	}
+	r.SetTypes()
 	return nil
 }
 
@@ -37,35 +54,54 @@ func (r *Result) ToJson() ([]byte, error) {
 
 // Print prints the result to the terminal
 func (r *Result) Print() {
+	if r.Provenance != nil {
+		fmt.Printf(" --Extracted from %s at %s\n", r.Provenance.NodeName(), r.Provenance.Timestamp.Format(time.RFC3339))
+	}
+	if len(r.Redacted) > 0 {
+		fmt.Printf(" --Redacted %d field(s)\n", len(r.Redacted))
+	}
 	for name, result := range r.Results {
 		fmt.Printf(" --Result for %s\n", name)
 		result.Print()
 	}
 }
 
+// Flatten returns the values in the result by <extractor>.<section>.<field>
+func (r *Result) Flatten() map[string]Value {
+	fields := map[string]Value{}
+	for name, data := range r.Results {
+		for sectionName, section := range data.Sections {
+			for key, value := range section {
+				fields[fmt.Sprintf("%s.%s.%s", name, sectionName, key)] = value
+			}
+		}
+	}
+	return fields
+}
+
 // AddCustomFields adds or updates an existing result with
 // custom metadata, either new or to overwrite
 func (r *Result) AddCustomFields(fields []string) {
 
 	for _, field := range fields {
 		if !strings.Contains(field, "=") {
-			fmt.Printf("warning: field %s does not contain an '=', skipping\n", field)
+			fmt.Fprintf(os.Stderr, "warning: field %s does not contain an '=', skipping\n", field)
 			continue
 		}
 		parts := strings.Split(field, "=")
 		if len(parts) < 2 {
-			fmt.Printf("warning: field %s has an empty value, skipping\n", field)
+			fmt.Fprintf(os.Stderr, "warning: field %s has an empty value, skipping\n", field)
 			continue
 		}
 
 		// No reason the value cannot have additional =
 		field = parts[0]
-		value := strings.Join(parts[1:], "=")
+		value := Unquote(strings.Join(parts[1:], "="))
 
 		// Get the extractor, section, and subfield from the field
 		f, err := ParseField(field)
 		if err != nil {
-			fmt.Printf(err.Error(), field)
+			fmt.Fprint(os.Stderr, err.Error())
 			continue
 		}
 
@@ -83,7 +119,7 @@ func (r *Result) AddCustomFields(fields []string) {
 			data.Sections[f.Section] = PluginSection{}
 		}
 		section := data.Sections[f.Section]
-		section[f.Field] = value
+		section[f.Field] = String(value)
 
 		// Wrap it back up!
 		data.Sections[f.Section] = section
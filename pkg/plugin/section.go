@@ -0,0 +1,110 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+var (
+	// How long to give an extraction to clean up (e.g., kill commands)
+	// after its context is done, before we stop waiting for it
+	cleanupGracePeriod = 2 * time.Second
+)
+
+// A SectionExtractor can extract one section at a time. This is an optional
+// capability that allows compspec to apply per-section timeouts.
+type SectionExtractor interface {
+	Extractor
+	ExtractSection(context.Context, string) (PluginSection, error)
+}
+
+// ExtractSection extracts a single named section. Not every extractor can
+// stop what it is doing (e.g., a library call) so if the context is done
+// first we return without waiting for the section to finish.
+func ExtractSection(ctx context.Context, e SectionExtractor, name string) (PluginSection, error) {
+	if ctx.Err() != nil {
+		return nil, fmt.Errorf("section %s: %w", name, ctx.Err())
+	}
+
+	type extracted struct {
+		section PluginSection
+		err     error
+	}
+	done := make(chan extracted, 1)
+	go func() {
+		section, err := e.ExtractSection(ctx, name)
+		done <- extracted{section, err}
+	}()
+
+	select {
+	case result := <-done:
+		return result.section, result.err
+	case <-ctx.Done():
+		waitForCleanup(done)
+		return nil, fmt.Errorf("section %s: %w", name, ctx.Err())
+	}
+}
+
+// ExtractSections extracts a list of sections in order, and is a suitable
+// Extract function for a SectionExtractor.
+func ExtractSections(
+	ctx context.Context,
+	e SectionExtractor,
+	names []string,
+	allowFail bool,
+) (PluginData, error) {
+
+	sections := Sections{}
+	data := PluginData{}
+
+	// Only extract the sections we asked for
+	for _, name := range names {
+		start := time.Now()
+		section, err := ExtractSection(ctx, e, name)
+		if IsFailure(err) && !allowFail {
+			return data, err
+		}
+		if section != nil {
+			sections[name] = section
+		}
+		data.SetStatus(name, NewStatus(err, time.Since(start), SourceOf(e, name)))
+	}
+	data.Sections = sections
+	return data, nil
+}
+
+// Extract runs an extractor, and like ExtractSection, returns early if
+// the context is done first.
+func Extract(ctx context.Context, e Extractor, allowFail bool) (PluginData, error) {
+	if ctx.Err() != nil {
+		return PluginData{}, ctx.Err()
+	}
+
+	type extracted struct {
+		data PluginData
+		err  error
+	}
+	done := make(chan extracted, 1)
+	go func() {
+		data, err := e.Extract(ctx, allowFail)
+		done <- extracted{data, err}
+	}()
+
+	select {
+	case result := <-done:
+		return result.data, result.err
+	case <-ctx.Done():
+		waitForCleanup(done)
+		return PluginData{}, ctx.Err()
+	}
+}
+
+// waitForCleanup waits a short time for an extraction that was cancelled
+// to return, so that anything that honors the context can clean up
+func waitForCleanup[T any](done <-chan T) {
+	select {
+	case <-done:
+	case <-time.After(cleanupGracePeriod):
+	}
+}
@@ -0,0 +1,144 @@
+package plugin
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// State is the outcome of extracting a section
+type State string
+
+const (
+	// The section was extracted
+	StateOK State = "ok"
+
+	// Some of the section was extracted, but there was an error
+	StatePartial State = "partial"
+
+	// The section could not be extracted
+	StateFailed State = "failed"
+
+	// The section was not extracted because there is nothing to find
+	// (e.g., MPI is not installed) or extraction was stopped
+	StateSkipped State = "skipped"
+
+	// The section cannot be extracted here (e.g., the kernel of an image)
+	StateUnsupported State = "unsupported"
+)
+
+// SectionStatus describes how extraction went for one section, so a
+// consumer can tell a section that is empty from one that failed
+type SectionStatus struct {
+	State    State    `json:"state"`
+	Error    string   `json:"error,omitempty"`
+	Duration Duration `json:"duration,omitempty"`
+
+	// Where the data came from (e.g., a file or command)
+	Source string `json:"source,omitempty"`
+}
+
+// A SourceReporter can say where the data for a section comes from.
+// This is an optional capability of an extractor.
+type SourceReporter interface {
+	Source(section string) string
+}
+
+// A StatusError is an error for a section that is not a failure, or
+// is only a partial one. Extractors create them with Skipped,
+// Unsupported, or Partial.
+type StatusError struct {
+	State State
+	Err   error
+}
+
+func (e *StatusError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *StatusError) Unwrap() error {
+	return e.Err
+}
+
+// Skipped returns an error that says there was nothing to extract
+func Skipped(format string, a ...interface{}) error {
+	return &StatusError{State: StateSkipped, Err: fmt.Errorf(format, a...)}
+}
+
+// Unsupported returns an error that says a section cannot be extracted here
+func Unsupported(format string, a ...interface{}) error {
+	return &StatusError{State: StateUnsupported, Err: fmt.Errorf(format, a...)}
+}
+
+// Partial wraps an error for a section that is returned with some data
+func Partial(err error) error {
+	return &StatusError{State: StatePartial, Err: err}
+}
+
+// StateOf returns the state for the error from extracting a section
+func StateOf(err error) State {
+	if err == nil {
+		return StateOK
+	}
+	var statusError *StatusError
+	if errors.As(err, &statusError) {
+		return statusError.State
+	}
+	return StateFailed
+}
+
+// IsFailure determines if an error from extracting a section is a failure,
+// as opposed to a section that was skipped, unsupported, or partial
+func IsFailure(err error) bool {
+	return StateOf(err) == StateFailed
+}
+
+// NewStatus returns the status for extracting a section
+func NewStatus(err error, duration time.Duration, source string) SectionStatus {
+	status := SectionStatus{State: StateOf(err), Duration: Duration(duration), Source: source}
+	if err != nil {
+		status.Error = err.Error()
+	}
+	return status
+}
+
+// Err returns the error for a status, which is nil when it is ok.
+// This is the inverse of NewStatus, e.g., for a status from a file.
+func (s SectionStatus) Err() error {
+	if s.State == StateOK || s.State == "" {
+		return nil
+	}
+	err := errors.New(s.Error)
+	if s.State == StateFailed {
+		return err
+	}
+	return &StatusError{State: s.State, Err: err}
+}
+
+// SourceOf returns the source of a section, if the extractor reports it
+func SourceOf(e Extractor, section string) string {
+	reporter, ok := e.(SourceReporter)
+	if !ok {
+		return ""
+	}
+	return reporter.Source(section)
+}
+
+// A Duration is serialized to json as a string (e.g., 1.5ms)
+type Duration time.Duration
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).Round(time.Microsecond).String())
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var value string
+	err := json.Unmarshal(data, &value)
+	if err != nil {
+		return err
+	}
+	duration, err := time.ParseDuration(value)
+	*d = Duration(duration)
+	return err
+}
@@ -0,0 +1,445 @@
+package plugin
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// A ValueType describes how a field value should be interpreted
+type ValueType string
+
+const (
+	StringType  ValueType = "string"
+	IntType     ValueType = "int"
+	FloatType   ValueType = "float"
+	BoolType    ValueType = "bool"
+	BytesType   ValueType = "bytes"
+	ListType    ValueType = "list"
+	VersionType ValueType = "version"
+)
+
+// IsKnown determines if a value type is known. Empty is a string.
+func (t ValueType) IsKnown() bool {
+	switch t {
+	case "", StringType, IntType, FloatType, BoolType, BytesType, ListType, VersionType:
+		return true
+	}
+	return false
+}
+
+var (
+	// Byte units are binary, as they are in /proc/meminfo (kB is KiB)
+	byteUnits = map[string]int64{
+		"":    1,
+		"b":   1,
+		"k":   1 << 10,
+		"kb":  1 << 10,
+		"kib": 1 << 10,
+		"m":   1 << 20,
+		"mb":  1 << 20,
+		"mib": 1 << 20,
+		"g":   1 << 30,
+		"gb":  1 << 30,
+		"gib": 1 << 30,
+		"t":   1 << 40,
+		"tb":  1 << 40,
+		"tib": 1 << 40,
+	}
+	regexBytes   = regexp.MustCompile(`^(\d+)\s*([A-Za-z]*)$`)
+	regexVersion = regexp.MustCompile(`^v?\d+(\.\d+)+([-+_.~]?[0-9A-Za-z]+)*$`)
+)
+
+// A Value is the value of an extracted field. Most values are strings,
+// but an extractor can provide a type so consumers don't need to parse.
+// Every value is serialized to JSON as a string (as it always was), and
+// the type is in the catalog of the extractor, so it can be set on load.
+type Value struct {
+	kind    ValueType
+	str     string
+	integer int64
+	float   float64
+	boolean bool
+	list    []string
+}
+
+// String returns a new string value
+func String(value string) Value {
+	return Value{kind: StringType, str: value}
+}
+
+// Int returns a new integer value
+func Int(value int64) Value {
+	return Value{kind: IntType, integer: value}
+}
+
+// Float returns a new floating point value
+func Float(value float64) Value {
+	return Value{kind: FloatType, float: value}
+}
+
+// Bool returns a new boolean value
+func Bool(value bool) Value {
+	return Value{kind: BoolType, boolean: value}
+}
+
+// List returns a new list of strings value
+func List(values []string) Value {
+	return Value{kind: ListType, list: values}
+}
+
+// Version returns a new version value
+func Version(value string) Value {
+	return Value{kind: VersionType, str: value}
+}
+
+// Bytes returns a new byte quantity value, for a quantity in a unit
+// (e.g., 16318036 and kB). The unit is kept for display.
+func Bytes(quantity int64, unit string) Value {
+	return Value{kind: BytesType, integer: quantity, str: unit}
+}
+
+// ParseBytes parses a byte quantity with an optional unit (e.g., 12288 KB)
+func ParseBytes(value string) (Value, error) {
+	match := regexBytes.FindStringSubmatch(strings.TrimSpace(value))
+	if match == nil {
+		return Value{}, fmt.Errorf("%s is not a byte quantity", value)
+	}
+	if _, ok := byteUnits[strings.ToLower(match[2])]; !ok {
+		return Value{}, fmt.Errorf("%s is not a known byte unit", match[2])
+	}
+	quantity, err := strconv.ParseInt(match[1], 10, 64)
+	if err != nil {
+		return Value{}, err
+	}
+	return Bytes(quantity, match[2]), nil
+}
+
+// ParseValue parses a string as the named type. An empty type is a string.
+func ParseValue(value string, kind ValueType) (Value, error) {
+	value = strings.TrimSpace(value)
+	switch kind {
+	case "", StringType:
+		return String(value), nil
+	case IntType:
+		integer, err := strconv.ParseInt(value, 10, 64)
+		return Int(integer), err
+	case FloatType:
+		float, err := strconv.ParseFloat(value, 64)
+		return Float(float), err
+	case BoolType:
+		boolean, err := strconv.ParseBool(value)
+		return Bool(boolean), err
+	case BytesType:
+		return ParseBytes(value)
+	case ListType:
+		return List(strings.Fields(value)), nil
+	case VersionType:
+		return Version(value), nil
+	}
+	return Value{}, fmt.Errorf("%s is not a known value type", kind)
+}
+
+// InferValue guesses the type of a string value. Dotted numbers are
+// versions, so "4.10" is newer than "4.9" when compared. Only true and
+// false are booleans (not t, F, or TRUE).
+func InferValue(value string) Value {
+	value = strings.TrimSpace(value)
+	if value == "true" || value == "false" {
+		return Bool(value == "true")
+	}
+	if integer, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return Int(integer)
+	}
+	if regexVersion.MatchString(value) {
+		return Version(value)
+	}
+	if float, err := strconv.ParseFloat(value, 64); err == nil {
+		return Float(float)
+	}
+	if quantity, err := ParseBytes(value); err == nil {
+		return quantity
+	}
+	return String(value)
+}
+
+// Type returns the type of the value
+func (v Value) Type() ValueType {
+	if v.kind == "" {
+		return StringType
+	}
+	return v.kind
+}
+
+// String returns the value as a string, which for bytes includes the
+// unit, and for lists is space separated (e.g., cpu flags)
+func (v Value) String() string {
+	switch v.kind {
+	case IntType:
+		return strconv.FormatInt(v.integer, 10)
+	case FloatType:
+		return strconv.FormatFloat(v.float, 'f', -1, 64)
+	case BoolType:
+		return strconv.FormatBool(v.boolean)
+	case ListType:
+		return strings.Join(v.list, " ")
+	case BytesType:
+		if v.str == "" {
+			return strconv.FormatInt(v.integer, 10)
+		}
+		return fmt.Sprintf("%d %s", v.integer, v.str)
+	}
+	return v.str
+}
+
+// Int returns the value as an integer. A string is parsed, so this
+// works for results that were saved before values had types.
+func (v Value) Int() (int64, bool) {
+	switch v.kind {
+	case IntType:
+		return v.integer, true
+	case "", StringType:
+		integer, err := strconv.ParseInt(strings.TrimSpace(v.str), 10, 64)
+		return integer, err == nil
+	}
+	return 0, false
+}
+
+// Float returns the value as a floating point number. Integers and
+// strings that parse as numbers are converted.
+func (v Value) Float() (float64, bool) {
+	switch v.kind {
+	case FloatType:
+		return v.float, true
+	case IntType:
+		return float64(v.integer), true
+	case "", StringType:
+		float, err := strconv.ParseFloat(strings.TrimSpace(v.str), 64)
+		return float, err == nil
+	}
+	return 0, false
+}
+
+// Bool returns the value as a boolean. A string is parsed.
+func (v Value) Bool() (bool, bool) {
+	switch v.kind {
+	case BoolType:
+		return v.boolean, true
+	case "", StringType:
+		boolean, err := strconv.ParseBool(strings.TrimSpace(v.str))
+		return boolean, err == nil
+	}
+	return false, false
+}
+
+// Bytes returns a byte quantity in bytes. A string is parsed.
+func (v Value) Bytes() (int64, bool) {
+	switch v.kind {
+	case BytesType:
+		return v.integer * byteUnits[strings.ToLower(v.str)], true
+	case "", StringType:
+		quantity, err := ParseBytes(v.str)
+		if err != nil {
+			return 0, false
+		}
+		return quantity.Bytes()
+	}
+	return 0, false
+}
+
+// List returns the value as a list. A string is split on whitespace.
+func (v Value) List() []string {
+	switch v.kind {
+	case ListType:
+		return v.list
+	case "", StringType:
+		return strings.Fields(v.str)
+	}
+	return []string{v.String()}
+}
+
+// Compare compares two values, returning -1, 0, or 1. Versions are
+// compared by component, numbers and byte quantities numerically, and
+// anything else as strings. If either value is a string, its type is
+// inferred from the other value.
+func (v Value) Compare(other Value) int {
+	a, b := v, other
+	if a.Type() == StringType && b.Type() != StringType {
+		a = coerce(a, b.Type())
+	} else if b.Type() == StringType && a.Type() != StringType {
+		b = coerce(b, a.Type())
+	}
+
+	// A dotted decimal (e.g., 4992.2) is inferred as a version, but is a
+	// number when compared with one
+	a, b = asNumber(a, b), asNumber(b, a)
+
+	switch {
+	case a.Type() == VersionType || b.Type() == VersionType:
+		return CompareVersions(a.String(), b.String())
+	case a.Type() == BytesType && b.Type() == BytesType:
+		x, _ := a.Bytes()
+		y, _ := b.Bytes()
+		return compareNumbers(float64(x), float64(y))
+	}
+	x, okA := a.Float()
+	y, okB := b.Float()
+	if okA && okB && a.Type() != BoolType && b.Type() != BoolType {
+		return compareNumbers(x, y)
+	}
+	return strings.Compare(a.String(), b.String())
+}
+
+// coerce parses a string value as a type, and otherwise infers it
+func coerce(v Value, kind ValueType) Value {
+	parsed, err := ParseValue(v.str, kind)
+	if err != nil {
+		return InferValue(v.str)
+	}
+	return parsed
+}
+
+// asNumber returns a version as a float when it is compared with a
+// number and it parses as one, and otherwise the version as it is
+func asNumber(v, other Value) Value {
+	if v.Type() != VersionType || (other.Type() != FloatType && other.Type() != IntType) {
+		return v
+	}
+	float, err := strconv.ParseFloat(v.String(), 64)
+	if err != nil {
+		return v
+	}
+	return Float(float)
+}
+
+// compareNumbers compares two numbers
+func compareNumbers(x, y float64) int {
+	if x < y {
+		return -1
+	}
+	if x > y {
+		return 1
+	}
+	return 0
+}
+
+// CompareVersions compares two version strings by component (split on
+// separators) where numeric components are compared as numbers, and
+// returns -1, 0, or 1. A version with extra components is newer.
+func CompareVersions(a, b string) int {
+	split := func(version string) []string {
+		return strings.FieldsFunc(strings.TrimPrefix(version, "v"), func(r rune) bool {
+			return strings.ContainsRune(".-+_~ ", r)
+		})
+	}
+	partsA, partsB := split(a), split(b)
+	for i := 0; i < len(partsA) && i < len(partsB); i++ {
+		x, errA := strconv.ParseInt(partsA[i], 10, 64)
+		y, errB := strconv.ParseInt(partsB[i], 10, 64)
+		if errA == nil && errB == nil {
+			if x != y {
+				return compareNumbers(float64(x), float64(y))
+			}
+			continue
+		}
+		if c := strings.Compare(partsA[i], partsB[i]); c != 0 {
+			return c
+		}
+	}
+	return compareNumbers(float64(len(partsA)), float64(len(partsB)))
+}
+
+// typedValue is a value as an object with its type, which we accept on
+// load, e.g., {"type": "version", "value": "4.1.1"}
+type typedValue struct {
+	Type  ValueType `json:"type"`
+	Value string    `json:"value"`
+}
+
+// MarshalJSON serializes a value as a string, so results have the same
+// format for every consumer (e.g., 4, true, and "16318036 kB" are all
+// strings). SetTypes gives values their types again on load.
+func (v Value) MarshalJSON() ([]byte, error) {
+	if v.kind == FloatType && (math.IsInf(v.float, 0) || math.IsNaN(v.float)) {
+		return nil, fmt.Errorf("%f cannot be serialized to JSON", v.float)
+	}
+	return json.Marshal(v.String())
+}
+
+// UnmarshalJSON loads a value, and a string is always a string value.
+// Numbers, booleans, and lists (e.g., from an external extractor) keep
+// their JSON type.
+func (v *Value) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 || string(data) == "null" {
+		*v = String("")
+		return nil
+	}
+	switch data[0] {
+	case '"':
+		var value string
+		err := json.Unmarshal(data, &value)
+		*v = String(value)
+		return err
+
+	case 't', 'f':
+		var value bool
+		err := json.Unmarshal(data, &value)
+		*v = Bool(value)
+		return err
+
+	case '[':
+		values := []interface{}{}
+		err := json.Unmarshal(data, &values)
+		if err != nil {
+			return err
+		}
+		list := []string{}
+		for _, value := range values {
+			list = append(list, fmt.Sprint(value))
+		}
+		*v = List(list)
+		return nil
+
+	case '{':
+		value := typedValue{}
+		err := json.Unmarshal(data, &value)
+		if err != nil {
+			return err
+		}
+		if value.Type == BytesType {
+			*v, err = ParseBytes(value.Value)
+			return err
+		}
+		*v, err = ParseValue(value.Value, value.Type)
+		return err
+	}
+
+	// Anything else is a number, and with a decimal or exponent a float
+	if !bytes.ContainsAny(data, ".eE") {
+		integer, err := strconv.ParseInt(string(data), 10, 64)
+		if err == nil {
+			*v = Int(integer)
+			return nil
+		}
+	}
+	float, err := strconv.ParseFloat(string(data), 64)
+	if err != nil {
+		return fmt.Errorf("%s is not a valid value", data)
+	}
+	*v = Float(float)
+	return nil
+}
+
+// NewSection returns a section of string values
+func NewSection(values map[string]string) PluginSection {
+	section := PluginSection{}
+	for key, value := range values {
+		section[key] = String(value)
+	}
+	return section
+}
@@ -0,0 +1,34 @@
+package plugin
+
+import (
+	"testing"
+)
+
+// TestCompare compares values of each type, including a filter (which
+// is inferred) with a value that has a type from the catalog
+func TestCompare(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    Value
+		other    Value
+		expected int
+	}{
+		{"float greater than a dotted filter", Float(4992.5), InferValue("4992.2"), 1},
+		{"float less than a dotted filter", Float(4992.123), InferValue("4992.2"), -1},
+		{"dotted filter less than a float", InferValue("4992.2"), Float(4992.5), -1},
+		{"float equal to a dotted filter", Float(4992.2), InferValue("4992.2"), 0},
+		{"int less than a dotted filter", Int(4992), InferValue("4992.2"), -1},
+		{"versions by component", Version("4.10"), Version("4.9"), 1},
+		{"version with a dotted filter", Version("4.10"), InferValue("4.9"), 1},
+		{"version with a string", Version("5.14.0"), String("5.4"), 1},
+		{"bytes with units", Bytes(2, "GB"), Bytes(1024, "MB"), 1},
+		{"ints", Int(4), Int(16), -1},
+		{"strings", String("rocky"), String("rocky"), 0},
+	}
+	for _, test := range tests {
+		result := test.value.Compare(test.other)
+		if result != test.expected {
+			t.Errorf("%s: %s compared with %s is %d, expected %d", test.name, test.value, test.other, result, test.expected)
+		}
+	}
+}
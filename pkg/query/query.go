@@ -0,0 +1,172 @@
+package query
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/compspec/compspec-go/pkg/plugin"
+)
+
+// globChars are the characters that make a pattern a glob
+const globChars = "*?["
+
+// A Query selects fields from a result with a pattern for the
+// <extractor>.<section>.<field>, and values with filters
+type Query struct {
+	Pattern plugin.Field
+	Filters []Filter
+}
+
+// A Filter is a comparison for a value (e.g., >=4.1). For = and != the
+// value can also be a glob (e.g., =Intel*).
+type Filter struct {
+	Operator string
+	Value    string
+}
+
+// A Row is a field that matches a query
+type Row struct {
+	Field string       `json:"field"`
+	Value plugin.Value `json:"value"`
+}
+
+// A Count is the number of fields with a distinct value
+type Count struct {
+	Value plugin.Value `json:"value"`
+	Count int          `json:"count"`
+}
+
+// New prepares a query for a pattern and filters. Each part of the pattern
+// is a glob, where * matches anything (including a dot), and missing parts
+// match everything (e.g., system is the same as system.*.*).
+func New(pattern string, filters []string) (Query, error) {
+	q := Query{}
+	parts := strings.Split(pattern, ".")
+	for len(parts) < 3 {
+		parts = append(parts, "*")
+	}
+	field, err := plugin.ParseField(strings.Join(parts, "."))
+	if err != nil {
+		return q, err
+	}
+	for _, part := range []string{field.Extractor, field.Section, field.Field} {
+		_, err := path.Match(part, "")
+		if err != nil || part == "" {
+			return q, fmt.Errorf("pattern %s is not valid, it should be <extractor>.<section>.<field> with globs", pattern)
+		}
+	}
+	q.Pattern = field
+
+	for _, filter := range filters {
+		operator, value, err := plugin.ParseOperator(filter)
+		if err != nil {
+			return q, err
+		}
+		q.Filters = append(q.Filters, Filter{Operator: operator, Value: value})
+	}
+	return q, nil
+}
+
+// Matches determines if a field and value match the query
+func (q Query) Matches(name string, value plugin.Value) bool {
+	field, err := plugin.ParseField(name)
+	if err != nil {
+		return false
+	}
+	if !plugin.MatchGlob(q.Pattern.Extractor, field.Extractor) ||
+		!plugin.MatchGlob(q.Pattern.Section, field.Section) ||
+		!plugin.MatchGlob(q.Pattern.Field, field.Field) {
+		return false
+	}
+	for _, filter := range q.Filters {
+		if !filter.Matches(value) {
+			return false
+		}
+	}
+	return true
+}
+
+// Matches determines if a value satisfies the filter. A glob is
+// matched against the value as text, and otherwise values are compared
+// by type (e.g., as versions or numbers).
+func (f Filter) Matches(value plugin.Value) bool {
+	if (f.Operator == "=" || f.Operator == "!=") && strings.ContainsAny(f.Value, globChars) {
+		matched := plugin.MatchGlob(f.Value, value.String())
+		return matched == (f.Operator == "=")
+	}
+	return value.Satisfies(f.Operator, plugin.InferValue(f.Value))
+}
+
+// Run returns the fields in the result that match, sorted by field
+func (q Query) Run(result plugin.Result) []Row {
+	rows := []Row{}
+	for name, value := range result.Flatten() {
+		if q.Matches(name, value) {
+			rows = append(rows, Row{Field: name, Value: value})
+		}
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		return rows[i].Field < rows[j].Field
+	})
+	return rows
+}
+
+// Distinct counts the rows for each distinct value, most common first
+func Distinct(rows []Row) []Count {
+	index := map[string]int{}
+	counts := []Count{}
+	for _, row := range rows {
+		key := row.Value.String()
+		i, ok := index[key]
+		if !ok {
+			index[key] = len(counts)
+			counts = append(counts, Count{Value: row.Value})
+			i = len(counts) - 1
+		}
+		counts[i].Count += 1
+	}
+	sort.SliceStable(counts, func(i, j int) bool {
+		if counts[i].Count != counts[j].Count {
+			return counts[i].Count > counts[j].Count
+		}
+		return counts[i].Value.String() < counts[j].Value.String()
+	})
+	return counts
+}
+
+// Plugins returns the extractors (and section, if it is not a glob) to
+// extract for the query, from the names of registered extractors. An
+// extractor that doesn't have a section for the query is left out, and
+// one that doesn't register its sections (an external extractor) is
+// extracted in full, since we only know its sections when it is run.
+func (q Query) Plugins() []string {
+	names := []string{}
+	for _, r := range plugin.Registered() {
+		if r.Kind != plugin.ExtractorKind || !plugin.MatchGlob(q.Pattern.Extractor, r.Name) {
+			continue
+		}
+		if len(r.Sections) == 0 {
+			names = append(names, r.Name)
+			continue
+		}
+		isGlob := strings.ContainsAny(q.Pattern.Section, globChars)
+		found := false
+		for _, section := range r.Sections {
+			if section == q.Pattern.Section || (isGlob && plugin.MatchGlob(q.Pattern.Section, section)) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			continue
+		}
+		name := r.Name
+		if !isGlob {
+			name = fmt.Sprintf("%s[%s]", r.Name, q.Pattern.Section)
+		}
+		names = append(names, name)
+	}
+	return names
+}
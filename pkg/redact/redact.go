@@ -0,0 +1,216 @@
+package redact
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/compspec/compspec-go/pkg/plugin"
+	"sigs.k8s.io/yaml"
+)
+
+// An Action is what we do to a field that should not be shared
+type Action string
+
+const (
+	// Drop removes the field from the result
+	Drop Action = "drop"
+
+	// Hash replaces the value with a sha256 of it, so values can still
+	// be compared (e.g., across nodes) without being known
+	Hash Action = "hash"
+
+	// Mask replaces the value with a fixed string
+	Mask Action = "mask"
+
+	// The value for a masked field
+	MaskedValue = "********"
+
+	// The prefix for a hashed value
+	HashPrefix = "sha256:"
+)
+
+// A Policy is an ordered list of rules, and the first rule that matches
+// a field is used. A policy file is yaml, for example:
+//
+//	rules:
+//	- field: kernel.boot.command_line
+//	  action: hash
+//	- field: nfd.network.*
+//	  action: drop
+type Policy struct {
+	Rules []Rule `json:"rules"`
+}
+
+// A Rule matches <extractor>.<section>.<field> with a glob, where *
+// matches anything (including a dot)
+type Rule struct {
+	Field  string `json:"field"`
+	Action Action `json:"action"`
+}
+
+// NewPolicy returns a policy from rules on the command line, which are
+// used first, and then the rules in a policy file (if there is one)
+func NewPolicy(rules []string, filename string) (Policy, error) {
+	policy := Policy{}
+	for _, spec := range rules {
+		rule, err := ParseRule(spec)
+		if err != nil {
+			return policy, err
+		}
+		policy.Rules = append(policy.Rules, rule)
+	}
+	if filename != "" {
+		loaded, err := Load(filename)
+		if err != nil {
+			return policy, err
+		}
+		policy.Rules = append(policy.Rules, loaded.Rules...)
+	}
+	return policy, nil
+}
+
+// Load reads and validates a policy file
+func Load(filename string) (Policy, error) {
+	policy := Policy{}
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return policy, err
+	}
+	err = yaml.UnmarshalStrict(content, &policy)
+	if err != nil {
+		return policy, fmt.Errorf("%s is not a valid redaction policy: %s", filename, err)
+	}
+	for _, rule := range policy.Rules {
+		err := rule.Validate()
+		if err != nil {
+			return policy, fmt.Errorf("%s is not a valid redaction policy: %s", filename, err)
+		}
+	}
+	return policy, nil
+}
+
+// ParseRule parses <field>[=<action>], where the action defaults to drop
+func ParseRule(spec string) (Rule, error) {
+	field, action, found := strings.Cut(spec, "=")
+	rule := Rule{Field: field, Action: Drop}
+	if found {
+		rule.Action = Action(action)
+	}
+	return rule, rule.Validate()
+}
+
+// Validate ensures the rule has a valid glob and known action
+func (r Rule) Validate() error {
+	if r.Field == "" {
+		return fmt.Errorf("a redaction rule needs a field")
+	}
+	_, err := path.Match(r.Field, "")
+	if err != nil {
+		return fmt.Errorf("redaction field %s is not a valid glob: %s", r.Field, err)
+	}
+	switch r.Action {
+	case Drop, Hash, Mask:
+		return nil
+	}
+	return fmt.Errorf("redaction action %q for %s is not known, choices are drop, hash, and mask", r.Action, r.Field)
+}
+
+// IsEmpty determines if the policy does not redact anything
+func (p Policy) IsEmpty() bool {
+	return len(p.Rules) == 0
+}
+
+// Action returns the action for a field, if a rule matches it
+func (p Policy) Action(field string) (Action, bool) {
+	for _, rule := range p.Rules {
+		if plugin.MatchGlob(rule.Field, field) {
+			return rule.Action, true
+		}
+	}
+	return "", false
+}
+
+// Apply redacts fields in the result, and records what was done to each.
+// Fields of the provenance that identify where it was extracted are named
+// provenance.<field> (e.g., provenance.hostname) and can be redacted too.
+func (p Policy) Apply(result *plugin.Result) {
+	if p.IsEmpty() {
+		return
+	}
+	p.applyProvenance(result)
+	for name, data := range result.Results {
+		for sectionName, section := range data.Sections {
+			for key, value := range section {
+				field := fmt.Sprintf("%s.%s.%s", name, sectionName, key)
+				action, ok := p.Action(field)
+				if !ok {
+					continue
+				}
+				switch action {
+				case Drop:
+					delete(section, key)
+				case Hash:
+					section[key] = hashValue(value)
+				case Mask:
+					section[key] = plugin.String(MaskedValue)
+				}
+				result.SetRedacted(field, string(action))
+			}
+		}
+	}
+}
+
+// applyProvenance redacts the fields of the provenance that identify the
+// host, root, or image, and the command (which can name them too)
+func (p Policy) applyProvenance(result *plugin.Result) {
+	provenance := result.Provenance
+	if provenance == nil {
+		return
+	}
+	fields := map[string]*string{
+		"provenance.hostname":  &provenance.Hostname,
+		"provenance.machineId": &provenance.MachineID,
+		"provenance.bootId":    &provenance.BootID,
+		"provenance.root":      &provenance.Root,
+		"provenance.image":     &provenance.Image,
+	}
+	for field, value := range fields {
+		action, ok := p.Action(field)
+		if !ok || *value == "" {
+			continue
+		}
+		switch action {
+		case Drop:
+			*value = ""
+		case Hash:
+			*value = hashValue(plugin.String(*value)).String()
+		case Mask:
+			*value = MaskedValue
+		}
+		result.SetRedacted(field, string(action))
+	}
+
+	action, ok := p.Action("provenance.command")
+	if !ok || len(provenance.Command) == 0 {
+		return
+	}
+	switch action {
+	case Drop:
+		provenance.Command = nil
+	case Hash:
+		provenance.Command = []string{hashValue(plugin.String(strings.Join(provenance.Command, " "))).String()}
+	case Mask:
+		provenance.Command = []string{MaskedValue}
+	}
+	result.SetRedacted("provenance.command", string(action))
+}
+
+// hashValue returns the sha256 of the value as text
+func hashValue(value plugin.Value) plugin.Value {
+	sum := sha256.Sum256([]byte(value.String()))
+	return plugin.String(HashPrefix + hex.EncodeToString(sum[:]))
+}
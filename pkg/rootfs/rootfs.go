@@ -0,0 +1,215 @@
+package rootfs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	securejoin "github.com/cyphar/filepath-securejoin"
+)
+
+// globChars are the characters that make a part of a pattern a glob
+const globChars = `*?[\`
+
+// A Root is the filesystem that extractors read from. The default (empty)
+// root is the host, and otherwise every path is resolved under the root
+// directory, e.g., a mounted container rootfs, /proc/<pid>/root of a
+// running container, or a recorded tree of files. Symbolic links are
+// resolved within the root, so an absolute link in a container rootfs
+// does not escape to the host.
+type Root struct {
+	path string
+
+	// The root is the filesystem of an image, so there is no running
+	// system (e.g., /proc or /sys) to describe
+	image bool
+}
+
+// Host returns the root for the host filesystem
+func Host() Root {
+	return Root{}
+}
+
+// New returns a root for a directory. An empty path or / is the host.
+func New(path string) (Root, error) {
+	if path == "" || filepath.Clean(path) == "/" {
+		return Host(), nil
+	}
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return Root{}, err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return Root{}, fmt.Errorf("root %s does not exist: %s", path, err)
+	}
+	if !info.IsDir() {
+		return Root{}, fmt.Errorf("root %s is not a directory", path)
+	}
+	return Root{path: path}, nil
+}
+
+// NewImage returns a root for the unpacked filesystem of an image
+func NewImage(path string) (Root, error) {
+	if path == "" || filepath.Clean(path) == "/" {
+		return Root{}, fmt.Errorf("an image root cannot be the host")
+	}
+	root, err := New(path)
+	root.image = true
+	return root, err
+}
+
+// IsImage determines if the root is the filesystem of an image
+func (r Root) IsImage() bool {
+	return r.image
+}
+
+// IsHost determines if the root is the host filesystem. Things that
+// cannot be redirected to a root (e.g., running commands) are only
+// meaningful for the host.
+func (r Root) IsHost() bool {
+	return r.path == ""
+}
+
+// String returns the directory of the root
+func (r Root) String() string {
+	if r.IsHost() {
+		return "/"
+	}
+	return r.path
+}
+
+// Path returns the path on the host for a path in the root
+func (r Root) Path(name string) (string, error) {
+	if r.IsHost() {
+		return name, nil
+	}
+	return securejoin.SecureJoin(r.path, name)
+}
+
+// ReadFile reads a file in the root
+func (r Root) ReadFile(name string) ([]byte, error) {
+	path, err := r.Path(name)
+	if err != nil {
+		return nil, err
+	}
+	return os.ReadFile(path)
+}
+
+// Open opens a file in the root for reading
+func (r Root) Open(name string) (*os.File, error) {
+	path, err := r.Path(name)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(path)
+}
+
+// ReadDir reads a directory in the root
+func (r Root) ReadDir(name string) ([]os.DirEntry, error) {
+	path, err := r.Path(name)
+	if err != nil {
+		return nil, err
+	}
+	return os.ReadDir(path)
+}
+
+// Stat returns file info for a path in the root
+func (r Root) Stat(name string) (fs.FileInfo, error) {
+	path, err := r.Path(name)
+	if err != nil {
+		return nil, err
+	}
+	return os.Stat(path)
+}
+
+// Readlink returns the target of a symbolic link in the root, which is
+// as it is written (e.g., relative), and is not resolved
+func (r Root) Readlink(name string) (string, error) {
+	dir, err := r.Path(filepath.Dir(name))
+	if err != nil {
+		return "", err
+	}
+	return os.Readlink(filepath.Join(dir, filepath.Base(name)))
+}
+
+// Exists determines if a path exists in the root. Like utils.PathExists,
+// an error other than the path not existing is returned.
+func (r Root) Exists(name string) (bool, error) {
+	_, err := r.Stat(name)
+	if err == nil {
+		return true, nil
+	}
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	return false, err
+}
+
+// Glob returns the paths in the root (not on the host) matching a pattern.
+// Like any other path, symbolic links are resolved within the root, so we
+// match one component of the pattern at a time in the resolved directory.
+func (r Root) Glob(pattern string) ([]string, error) {
+	if r.IsHost() {
+		return filepath.Glob(pattern)
+	}
+	_, err := filepath.Match(pattern, "")
+	if err != nil {
+		return nil, err
+	}
+	paths := []string{"/"}
+	for _, part := range strings.Split(filepath.Clean("/"+pattern), string(filepath.Separator)) {
+		if part == "" {
+			continue
+		}
+		next := []string{}
+		for _, path := range paths {
+			if !strings.ContainsAny(part, globChars) {
+				next = append(next, filepath.Join(path, part))
+				continue
+			}
+			entries, err := r.ReadDir(path)
+			if err != nil {
+				continue
+			}
+			for _, entry := range entries {
+				matched, _ := filepath.Match(part, entry.Name())
+				if matched {
+					next = append(next, filepath.Join(path, entry.Name()))
+				}
+			}
+		}
+		paths = next
+	}
+
+	// Like filepath.Glob, only paths that exist are matches
+	matches := []string{}
+	for _, path := range paths {
+		exists, err := r.Exists(path)
+		if err == nil && exists {
+			matches = append(matches, path)
+		}
+	}
+	return matches, nil
+}
+
+// rootKey is the context key for the root
+type rootKey struct{}
+
+// WithRoot returns a context that carries a root for extractors
+func WithRoot(ctx context.Context, root Root) context.Context {
+	return context.WithValue(ctx, rootKey{}, root)
+}
+
+// FromContext returns the root from a context, which is the host if not set
+func FromContext(ctx context.Context) Root {
+	root, ok := ctx.Value(rootKey{}).(Root)
+	if !ok {
+		return Host()
+	}
+	return root
+}
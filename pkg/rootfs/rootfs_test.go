@@ -0,0 +1,59 @@
+package rootfs
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// TestGlobSymlink ensures that a link in the root is resolved in the root,
+// and a glob does not match files on the host
+func TestGlobSymlink(t *testing.T) {
+	host := t.TempDir()
+	for _, name := range []string{"secret-a", "secret-b"} {
+		err := os.WriteFile(filepath.Join(host, name), []byte("host"), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The root has a link to the same (absolute) path, which it doesn't have
+	dir := t.TempDir()
+	err := os.MkdirAll(filepath.Join(dir, "etc"), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(filepath.Join(dir, "etc", "secret-c"), []byte("root"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.Symlink(host, filepath.Join(dir, "link"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.Symlink("/etc", filepath.Join(dir, "etc-link"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, err := New(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string][]string{
+		"/link/secret-*":     {},
+		"/etc-link/secret-*": {"/etc-link/secret-c"},
+		"/*/secret-c":        {"/etc/secret-c", "/etc-link/secret-c"},
+		"/etc/[a-z]*":        {"/etc/secret-c"},
+	}
+	for pattern, expected := range tests {
+		matches, err := root.Glob(pattern)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(matches, expected) {
+			t.Errorf("glob %s matched %v, expected %v", pattern, matches, expected)
+		}
+	}
+}
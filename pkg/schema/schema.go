@@ -0,0 +1,133 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/compspec/compspec-go/pkg/plugin"
+)
+
+// The JSON Schema draft that we write
+const Draft = "https://json-schema.org/draft/2020-12/schema"
+
+// A Schema is the subset of JSON Schema that we need to describe the
+// data for an extractor, as it is saved in a result
+type Schema struct {
+	Schema               string             `json:"$schema,omitempty"`
+	Title                string             `json:"title,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	PatternProperties    map[string]*Schema `json:"patternProperties,omitempty"`
+	AdditionalProperties *bool              `json:"additionalProperties,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	Examples             []string           `json:"examples,omitempty"`
+}
+
+// ForExtractor returns the schema for the data of an extractor, which
+// is generated from the fields it describes (the field catalog). Every
+// field in a section must match a pattern, and have the type of each
+// pattern it matches.
+func ForExtractor(e plugin.Extractor) (*Schema, error) {
+	cataloged, ok := e.(plugin.Cataloged)
+	if !ok || len(cataloged.Fields()) == 0 {
+		return nil, fmt.Errorf("extractor %s does not describe its fields", e.Name())
+	}
+	sections := &Schema{Type: "object", Properties: map[string]*Schema{}, AdditionalProperties: boolean(false)}
+	for _, name := range e.Sections() {
+		section := &Schema{Type: "object", PatternProperties: map[string]*Schema{}, AdditionalProperties: boolean(false)}
+		for _, field := range cataloged.Fields() {
+			if field.Section != name {
+				continue
+			}
+			value := ForValue(field.Type)
+			value.Description = field.Description
+			if field.Unit != "" {
+				value.Description = fmt.Sprintf("%s (%s)", field.Description, field.Unit)
+			}
+
+			if field.Example != "" {
+				value.Examples = []string{field.Example}
+			}
+			section.PatternProperties[Pattern(field.Name)] = value
+		}
+		sections.Properties[name] = section
+	}
+	return &Schema{
+		Schema:      Draft,
+		Title:       fmt.Sprintf("compspec %s extractor", e.Name()),
+		Description: e.Description(),
+		Type:        "object",
+		Properties: map[string]*Schema{
+			"sections": sections,
+			"status":   {Type: "object", Description: "status of each section"},
+		},
+		AdditionalProperties: boolean(false),
+	}, nil
+}
+
+// ForValue returns the schema for a value of a type, as it is saved.
+// Every value is saved as a string, so a type is a pattern of the string,
+// and the format is the type (e.g., int or version).
+func ForValue(kind plugin.ValueType) *Schema {
+	switch kind {
+	case plugin.AnyType:
+		return &Schema{}
+	case "", plugin.StringType:
+		return &Schema{Type: "string"}
+	}
+	value := &Schema{Type: "string", Format: string(kind)}
+	switch kind {
+	case plugin.IntType:
+		value.Pattern = `^[+-]?[0-9]+$`
+	case plugin.FloatType:
+		value.Pattern = `^[+-]?([0-9]+[.]?[0-9]*|[.][0-9]+)([eE][+-]?[0-9]+)?$`
+	case plugin.BoolType:
+		value.Enum = []string{"true", "false"}
+	case plugin.BytesType:
+		value.Pattern = `^[0-9]+\s*[A-Za-z]*$`
+	}
+	return value
+}
+
+// Pattern converts a field glob (where * matches anything, including
+// a dot) into an anchored regular expression
+func Pattern(glob string) string {
+	var pattern strings.Builder
+	pattern.WriteString("^")
+	for i := 0; i < len(glob); i++ {
+		switch c := glob[i]; c {
+		case '*':
+			pattern.WriteString(".*")
+		case '?':
+			pattern.WriteString(".")
+		case '[':
+			end := strings.IndexByte(glob[i:], ']')
+			if end < 0 {
+				pattern.WriteString(regexp.QuoteMeta(glob[i:]))
+				i = len(glob)
+				continue
+			}
+			pattern.WriteString(glob[i : i+end+1])
+			i += end
+		default:
+			pattern.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	pattern.WriteString("$")
+	return pattern.String()
+}
+
+// ToJson serializes a schema to json
+func (s *Schema) ToJson() ([]byte, error) {
+	return json.MarshalIndent(s, "", "  ")
+}
+
+// boolean returns a pointer to a bool, for additionalProperties
+func boolean(value bool) *bool {
+	return &value
+}
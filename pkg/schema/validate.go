@@ -0,0 +1,184 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/compspec/compspec-go/pkg/plugin"
+)
+
+// LoadValidated loads a saved result, after checking it against the schema
+// of each extractor in it
+func LoadValidated(filename string) (plugin.Result, error) {
+	result := plugin.Result{}
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return result, err
+	}
+	err = ValidateJSON(content)
+	if err != nil {
+		return result, fmt.Errorf("%s is not valid: %s", filename, err)
+	}
+	return result, result.Load(filename)
+}
+
+// Validate checks a result against the schema of each extractor, as
+// the result would be saved
+func Validate(result *plugin.Result) error {
+	content, err := result.ToJson()
+	if err != nil {
+		return err
+	}
+	return ValidateJSON(content)
+}
+
+// ValidateJSON checks a result (as json) against the schema of each
+// extractor, except for fields that were redacted. An extractor that is not known here, or that does not
+// describe its fields, cannot be checked, and we warn about it.
+func ValidateJSON(content []byte) error {
+	result := struct {
+		Results map[string]struct {
+			Sections map[string]interface{} `json:"sections"`
+		} `json:"results"`
+		Redacted map[string]string `json:"redacted"`
+	}{}
+	err := json.Unmarshal(content, &result)
+	if err != nil {
+		return err
+	}
+	problems := []string{}
+	for _, name := range sortedKeys(result.Results) {
+		p, err := plugin.Described(name)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "warning: cannot validate %s: %s\n", name, err)
+			continue
+		}
+		extractor, ok := p.(plugin.Extractor)
+		if !ok {
+			return fmt.Errorf("%s is not an extractor", name)
+		}
+		s, err := ForExtractor(extractor)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "warning: cannot validate %s: %s\n", name, err)
+			continue
+		}
+
+		// Sections are checked one at a time, so a problem is named by the field
+		sections := s.Properties["sections"]
+		for _, section := range sortedKeys(result.Results[name].Sections) {
+			path := name + "." + section
+			sectionSchema, ok := sections.Properties[section]
+			if !ok {
+				problems = append(problems, fmt.Sprintf("%s is not a known section", path))
+				continue
+			}
+			fields := result.Results[name].Sections[section]
+
+			// A redacted value (e.g., masked or hashed) is not the type of the field
+			if object, ok := fields.(map[string]interface{}); ok {
+				for field := range object {
+					if _, ok := result.Redacted[path+"."+field]; ok {
+						delete(object, field)
+					}
+				}
+			}
+			problems = append(problems, sectionSchema.Check(path, fields)...)
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("%d field(s) do not match the schema:\n  %s", len(problems), strings.Join(problems, "\n  "))
+	}
+	return nil
+}
+
+// Check checks a value (decoded from json) against the schema, and returns
+// a problem for each part of it that does not match. This is the part of
+// JSON Schema that we write, so the schema we give out is what we check.
+func (s *Schema) Check(path string, value interface{}) []string {
+	if s.Type != "" && typeOf(value) != s.Type {
+		return []string{fmt.Sprintf("%s is a %s, expected %s", path, typeOf(value), s.Type)}
+	}
+	problems := []string{}
+	if text, ok := value.(string); ok {
+		if len(s.Enum) > 0 && !contains(s.Enum, text) {
+			problems = append(problems, fmt.Sprintf("%s is %q, not a valid %s (%s)", path, text, s.kind(), strings.Join(s.Enum, " or ")))
+		}
+		if s.Pattern != "" && !regexp.MustCompile(s.Pattern).MatchString(text) {
+			problems = append(problems, fmt.Sprintf("%s is %q, not a valid %s", path, text, s.kind()))
+		}
+	}
+	object, ok := value.(map[string]interface{})
+	if !ok {
+		return problems
+	}
+	for _, key := range sortedKeys(object) {
+		field := path + "." + key
+		known := false
+		if property, ok := s.Properties[key]; ok {
+			known = true
+			problems = append(problems, property.Check(field, object[key])...)
+		}
+
+		// A field can match more than one pattern, and must match each
+		for _, pattern := range sortedKeys(s.PatternProperties) {
+			if !regexp.MustCompile(pattern).MatchString(key) {
+				continue
+			}
+			known = true
+			problems = append(problems, s.PatternProperties[pattern].Check(field, object[key])...)
+		}
+		if !known && s.AdditionalProperties != nil && !*s.AdditionalProperties {
+			problems = append(problems, fmt.Sprintf("%s is not a known field", field))
+		}
+	}
+	return problems
+}
+
+// kind is the format of a value (e.g., int), or the json type
+func (s *Schema) kind() string {
+	if s.Format != "" {
+		return s.Format
+	}
+	return s.Type
+}
+
+// typeOf returns the json type of a decoded value
+func typeOf(value interface{}) string {
+	switch value.(type) {
+	case string:
+		return "string"
+	case float64:
+		return "number"
+	case bool:
+		return "boolean"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return "null"
+}
+
+// contains determines if a list has an item
+func contains(items []string, item string) bool {
+	for _, each := range items {
+		if each == item {
+			return true
+		}
+	}
+	return false
+}
+
+// sortedKeys returns the keys of a map, sorted
+func sortedKeys[T any](m map[string]T) []string {
+	keys := []string{}
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
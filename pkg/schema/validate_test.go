@@ -0,0 +1,102 @@
+package schema
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/compspec/compspec-go/pkg/plugin"
+)
+
+// testExtractor has a field of each type, to check values as they are saved
+type testExtractor struct{}
+
+func (e testExtractor) Name() string        { return "schematest" }
+func (e testExtractor) Description() string { return "extractor to test the schema" }
+func (e testExtractor) Sections() []string  { return []string{"values"} }
+
+func (e testExtractor) Extract(ctx context.Context, allowFail bool) (plugin.PluginData, error) {
+	return plugin.PluginData{}, nil
+}
+
+func (e testExtractor) Fields() []plugin.FieldSpec {
+	return []plugin.FieldSpec{
+		{Section: "values", Name: "string", Description: "a string"},
+		{Section: "values", Name: "int", Type: plugin.IntType, Description: "an int"},
+		{Section: "values", Name: "float", Type: plugin.FloatType, Description: "a float"},
+		{Section: "values", Name: "bool", Type: plugin.BoolType, Description: "a bool"},
+		{Section: "values", Name: "list", Type: plugin.ListType, Description: "a list"},
+		{Section: "values", Name: "bytes", Type: plugin.BytesType, Description: "a byte quantity"},
+		{Section: "values", Name: "version", Type: plugin.VersionType, Description: "a version"},
+		{Section: "values", Name: "any.*", Type: plugin.AnyType, Description: "anything"},
+	}
+}
+
+func init() {
+	plugin.Register(plugin.Registration{
+		Name:     "schematest",
+		Kind:     plugin.ExtractorKind,
+		Sections: []string{"values"},
+		New: func(sections []string) (plugin.Plugin, error) {
+			return testExtractor{}, nil
+		},
+	})
+}
+
+// TestValidate checks that every type of value is valid in the schema as
+// it is saved, and that values that do not parse as the type are not
+func TestValidate(t *testing.T) {
+	result := plugin.Result{Results: map[string]plugin.PluginData{
+		"schematest": {Sections: plugin.Sections{"values": plugin.PluginSection{
+			"string":  plugin.String("debian"),
+			"int":     plugin.Int(-4),
+			"float":   plugin.Float(5000),
+			"bool":    plugin.Bool(false),
+			"list":    plugin.List([]string{"fpu", "vme"}),
+			"bytes":   plugin.Bytes(16318036, "kB"),
+			"version": plugin.Version("4.1.1"),
+			"any.one": plugin.Float(0.5),
+		}}},
+	}}
+	err := Validate(&result)
+	if err != nil {
+		t.Fatalf("a result with a value of each type is not valid: %s", err)
+	}
+
+	invalid := map[string]plugin.Value{
+		"int":     plugin.String("4.1"),
+		"float":   plugin.String("fast"),
+		"bool":    plugin.String("yes"),
+		"bytes":   plugin.String("16 GB of memory"),
+		"unknown": plugin.String("value"),
+	}
+	for field, value := range invalid {
+		result.Results["schematest"].Sections["values"][field] = value
+	}
+	err = Validate(&result)
+	if err == nil {
+		t.Fatalf("a result with invalid values is valid")
+	}
+	for field := range invalid {
+		if !strings.Contains(err.Error(), "schematest.values."+field+" ") {
+			t.Errorf("%s is not a problem in: %s", field, err)
+		}
+	}
+}
+
+// TestValidateRedacted checks that a redacted value (which is not the type
+// of the field) is valid
+func TestValidateRedacted(t *testing.T) {
+	result := plugin.Result{Results: map[string]plugin.PluginData{
+		"schematest": {Sections: plugin.Sections{"values": plugin.PluginSection{
+			"int":   plugin.String("********"),
+			"bytes": plugin.String("sha256:5e884898da28047151d0e56f8dc6292773603d0d"),
+		}}},
+	}}
+	result.SetRedacted("schematest.values.int", "mask")
+	result.SetRedacted("schematest.values.bytes", "hash")
+	err := Validate(&result)
+	if err != nil {
+		t.Fatalf("a result with redacted values is not valid: %s", err)
+	}
+}
@@ -1,18 +1,54 @@
 package utils
 
 import (
+	"context"
+	"os"
 	"os/exec"
+	"syscall"
+	"time"
+)
+
+var (
+	// How long to wait for output pipes to close after a command is killed
+	commandWaitDelay = 2 * time.Second
 )
 
 // RunCommand runs an executable (name)
 func RunCommand(args []string) (string, error) {
+	return RunCommandContext(context.Background(), args)
+}
+
+// RunCommandContext runs an executable (name), killing it (and any
+// children it started) if the context is done before it finishes
+func RunCommandContext(ctx context.Context, args []string) (string, error) {
+	return RunCommandEnv(ctx, args, nil)
+}
+
+// RunCommandEnv is RunCommandContext with extra environment variables
+// (e.g., KEY=value) added to the environment of the current process
+func RunCommandEnv(ctx context.Context, args []string, env []string) (string, error) {
 
 	executable := args[0]
 	args = args[1:]
 
-	cmd := exec.Command(executable, args...)
+	cmd := exec.CommandContext(ctx, executable, args...)
+	if len(env) > 0 {
+		cmd.Env = append(os.Environ(), env...)
+	}
+
+	// Put the command in its own process group so we can kill the
+	// whole group, e.g., mpirun and the processes it has launched
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+	cmd.WaitDelay = commandWaitDelay
+
 	out, err := cmd.Output()
 	if err != nil {
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
 		return "", err
 	}
 	return string(out), nil
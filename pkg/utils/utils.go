@@ -7,6 +7,8 @@ import (
 	"math"
 	"math/rand"
 	"os"
+	"sort"
+	"strconv"
 	"strings"
 )
 
@@ -133,3 +135,52 @@ func RandomSort(items []string) []string {
 	}
 	return items
 }
+
+// ParseCPUList parses a cpu list from sysfs (e.g., 0-3,8,10-11) into
+// sorted cpu numbers. An empty list has no cpus.
+func ParseCPUList(list string) ([]int, error) {
+	cpus := []int{}
+	list = strings.TrimSpace(list)
+	if list == "" {
+		return cpus, nil
+	}
+	for _, part := range strings.Split(list, ",") {
+		first, last, isRange := strings.Cut(part, "-")
+		start, err := strconv.Atoi(first)
+		if err != nil {
+			return cpus, fmt.Errorf("%s is not a valid cpu list", list)
+		}
+		end := start
+		if isRange {
+			end, err = strconv.Atoi(last)
+			if err != nil || end < start {
+				return cpus, fmt.Errorf("%s is not a valid cpu list", list)
+			}
+		}
+		for cpu := start; cpu <= end; cpu++ {
+			cpus = append(cpus, cpu)
+		}
+	}
+	sort.Ints(cpus)
+	return cpus, nil
+}
+
+// FormatCPUList formats cpu numbers as a cpu list, like sysfs
+func FormatCPUList(cpus []int) string {
+	sorted := append([]int{}, cpus...)
+	sort.Ints(sorted)
+	parts := []string{}
+	for i := 0; i < len(sorted); {
+		j := i
+		for j+1 < len(sorted) && sorted[j+1] == sorted[j]+1 {
+			j++
+		}
+		if i == j {
+			parts = append(parts, strconv.Itoa(sorted[i]))
+		} else {
+			parts = append(parts, fmt.Sprintf("%d-%d", sorted[i], sorted[j]))
+		}
+		i = j + 1
+	}
+	return strings.Join(parts, ",")
+}
@@ -0,0 +1,22 @@
+// Package builtin registers the plugins that are compiled into compspec.
+// Import it for side effects to make them available to plugins.GetPlugins:
+//
+//	import _ "github.com/compspec/compspec-go/plugins/builtin"
+//
+// Private plugins can be compiled in the same way, by importing a package
+// that calls plugin.Register in its init function.
+package builtin
+
+import (
+	// Creators
+	_ "github.com/compspec/compspec-go/plugins/creators/artifact"
+	_ "github.com/compspec/compspec-go/plugins/creators/cluster"
+
+	// Extractors
+	_ "github.com/compspec/compspec-go/plugins/extractors/gpu"
+	_ "github.com/compspec/compspec-go/plugins/extractors/interconnect"
+	_ "github.com/compspec/compspec-go/plugins/extractors/kernel"
+	_ "github.com/compspec/compspec-go/plugins/extractors/library"
+	_ "github.com/compspec/compspec-go/plugins/extractors/nfd"
+	_ "github.com/compspec/compspec-go/plugins/extractors/system"
+)
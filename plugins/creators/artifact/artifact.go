@@ -1,10 +1,15 @@
 package artifact
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"runtime"
+	"strings"
 
+	"github.com/compspec/compspec-go/pkg/image"
 	"github.com/compspec/compspec-go/pkg/plugin"
+	"github.com/compspec/compspec-go/pkg/redact"
 	"github.com/compspec/compspec-go/pkg/types"
 	p "github.com/compspec/compspec-go/plugins"
 	"sigs.k8s.io/yaml"
@@ -15,6 +20,18 @@ const (
 	CreatorDescription = "describe an application or environment"
 )
 
+// init registers the creator so it can be found by name
+func init() {
+	plugin.Register(plugin.Registration{
+		Name:        CreatorName,
+		Description: CreatorDescription,
+		Kind:        plugin.CreatorKind,
+		New: func([]string) (plugin.Plugin, error) {
+			return NewPlugin()
+		},
+	})
+}
+
 type ArtifactCreator struct{}
 
 func (c ArtifactCreator) Description() string {
@@ -25,16 +42,6 @@ func (c ArtifactCreator) Name() string {
 	return CreatorName
 }
 
-func (c ArtifactCreator) Sections() []string {
-	return []string{}
-}
-
-func (c ArtifactCreator) Extract(allowFail bool) (plugin.PluginData, error) {
-	return plugin.PluginData{}, nil
-}
-func (c ArtifactCreator) IsCreator() bool   { return true }
-func (c ArtifactCreator) IsExtractor() bool { return false }
-
 // Create generates the desired output
 func (c ArtifactCreator) Create(options plugin.PluginOptions) error {
 
@@ -56,16 +63,79 @@ func (c ArtifactCreator) Create(options plugin.PluginOptions) error {
 		return err
 	}
 
+	// Schemas (e.g., from a config) are given as <name>=<url>
+	for _, spec := range options.ListOpts["schemas"] {
+		name, url, found := strings.Cut(spec, "=")
+		if !found || name == "" {
+			return fmt.Errorf("schema %s is not in the format <name>=<url>", spec)
+		}
+		if request.Metadata.Schemas == nil {
+			request.Metadata.Schemas = map[string]string{}
+		}
+		request.Metadata.Schemas[name] = url
+	}
+
 	// Right now we only know about extractors, when we define subfields
-	// we can further filter here.
-	extractors := request.GetExtractors()
-	plugins, err := p.GetPlugins(extractors)
+	// we can further filter here. Fields that are not backed by a registered
+	// extractor (e.g., custom.build.date) must be added with -a
+	custom := map[string]bool{}
+	for _, field := range fields {
+		name, _, _ := strings.Cut(field, "=")
+		f, err := plugin.ParseField(name)
+		if err == nil {
+			custom[f.Extractor] = true
+		}
+	}
+	extractors := []string{}
+	for _, name := range request.GetExtractors() {
+		registration, err := plugin.Lookup(name)
+		if err != nil {
+			if custom[name] {
+				continue
+			}
+			return fmt.Errorf("%s (or add its fields with -a)", err)
+		}
+		if registration.Kind != plugin.ExtractorKind {
+			return fmt.Errorf("plugin %s is not an extractor", name)
+		}
+		extractors = append(extractors, name)
+	}
+
+	// No extractors would be all of them, but only custom fields are needed
+	plugins := p.PluginsRequest{}
+	if len(extractors) > 0 {
+		plugins, err = p.GetPlugins(extractors)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Options for extractors are given as <plugin>.<option>=<value>
+	raw, err := plugin.ParseOptionSpecs(options.ListOpts["options"])
+	if err != nil {
+		return err
+	}
+	err = plugins.SetOptions(raw)
+	if err != nil {
+		return err
+	}
+
+	// Extraction can be for another root (e.g., a container rootfs) or image
+	root, cleanup, err := image.OpenRoot(options.StrOpts["root"], options.StrOpts["image"], options.StrOpts["platform"])
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	// Redaction rules (e.g., from a config) are given as <field>=<action>
+	policy, err := redact.NewPolicy(options.ListOpts["redact"], "")
 	if err != nil {
 		return err
 	}
 
 	// Finally, add custom fields and extract metadata
-	result, err := plugins.Extract(allowFail)
+	extractOptions := p.ExtractOptions{AllowFail: allowFail, Jobs: runtime.NumCPU(), Root: root, Redact: policy}
+	result, err := plugins.Extract(context.Background(), extractOptions)
 	if err != nil {
 		return err
 	}
@@ -134,7 +204,7 @@ func PopulateExtractors(result *plugin.Result, request *types.CompatibilityReque
 			// Get the extractor, section, and subfield from the extractor lookup key
 			f, err := plugin.ParseField(extractorKey)
 			if err != nil {
-				fmt.Printf("warning: cannot parse %s: %s, setting to empty\n", key, extractorKey)
+				fmt.Fprintf(os.Stderr, "warning: cannot parse %s: %s, setting to empty\n", key, extractorKey)
 				compat.Attributes[key] = ""
 				continue
 			}
@@ -142,15 +212,20 @@ func PopulateExtractors(result *plugin.Result, request *types.CompatibilityReque
 			// If we get here, we can parse it and look it up in our result metadata
 			extractor, ok := result.Results[f.Extractor]
 			if !ok {
-				fmt.Printf("warning: extractor %s is unknown, setting to empty\n", f.Extractor)
+				fmt.Fprintf(os.Stderr, "warning: extractor %s is unknown, setting to empty\n", f.Extractor)
 				compat.Attributes[key] = ""
 				continue
 			}
 
 			// Now get the section
 			section, ok := extractor.Sections[f.Section]
+			if status, known := extractor.Status[f.Section]; !ok && known {
+				fmt.Fprintf(os.Stderr, "warning: section %s.%s is %s (%s), setting to empty\n", f.Extractor, f.Section, status.State, status.Error)
+				compat.Attributes[key] = ""
+				continue
+			}
 			if !ok {
-				fmt.Printf("warning: section %s.%s is unknown, setting to empty\n", f.Extractor, f.Section)
+				fmt.Fprintf(os.Stderr, "warning: section %s.%s is unknown, setting to empty\n", f.Extractor, f.Section)
 				compat.Attributes[key] = ""
 				continue
 			}
@@ -158,13 +233,13 @@ func PopulateExtractors(result *plugin.Result, request *types.CompatibilityReque
 			// Now get the value!
 			value, ok := section[f.Field]
 			if !ok {
-				fmt.Printf("warning: field %s.%s.%s is unknown, setting to empty\n", f.Extractor, f.Section, f.Field)
+				fmt.Fprintf(os.Stderr, "warning: field %s.%s.%s is unknown, setting to empty\n", f.Extractor, f.Section, f.Field)
 				compat.Attributes[key] = ""
 				continue
 			}
 
 			// If we get here - we found it! Hooray!
-			compat.Attributes[key] = value
+			compat.Attributes[key] = value.String()
 		}
 
 		// Update the compatibiity
@@ -174,12 +249,8 @@ func PopulateExtractors(result *plugin.Result, request *types.CompatibilityReque
 	return request, nil
 }
 
-func (c ArtifactCreator) Validate() bool {
-	return true
-}
-
 // NewPlugin creates a new ArtifactCreator
-func NewPlugin() (plugin.PluginInterface, error) {
+func NewPlugin() (plugin.Creator, error) {
 	c := ArtifactCreator{}
 	return c, nil
 }
@@ -5,18 +5,39 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
+	"sort"
 	"strconv"
+	"strings"
 
 	"github.com/compspec/compspec-go/pkg/graph"
 	"github.com/compspec/compspec-go/pkg/plugin"
+	"github.com/compspec/compspec-go/pkg/redact"
 	"github.com/compspec/compspec-go/pkg/utils"
 )
 
+var (
+	// Physical cores of a socket in the system topology
+	regexSocketCores = regexp.MustCompile(`^socket\.(\d+)\.cores$`)
+)
+
 const (
 	CreatorName        = "cluster"
 	CreatorDescription = "create cluster of nodes"
 )
 
+// init registers the creator so it can be found by name
+func init() {
+	plugin.Register(plugin.Registration{
+		Name:        CreatorName,
+		Description: CreatorDescription,
+		Kind:        plugin.CreatorKind,
+		New: func([]string) (plugin.Plugin, error) {
+			return NewPlugin()
+		},
+	})
+}
+
 type ClusterCreator struct{}
 
 func (c ClusterCreator) Description() string {
@@ -27,16 +48,6 @@ func (c ClusterCreator) Name() string {
 	return CreatorName
 }
 
-func (c ClusterCreator) Sections() []string {
-	return []string{}
-}
-
-func (c ClusterCreator) Extract(allowFail bool) (plugin.PluginData, error) {
-	return plugin.PluginData{}, nil
-}
-func (c ClusterCreator) IsCreator() bool   { return true }
-func (c ClusterCreator) IsExtractor() bool { return false }
-
 // Create generates the desired output
 func (c ClusterCreator) Create(options plugin.PluginOptions) error {
 
@@ -49,6 +60,9 @@ func (c ClusterCreator) Create(options plugin.PluginOptions) error {
 	// 	Results map[string]plugin.PluginData `json:"extractors,omitempty"`
 	nodes := map[string]plugin.Result{}
 
+	// The same machine extracted twice is one node
+	machines := map[string]string{}
+
 	nodeFiles, err := os.ReadDir(nodesDir)
 	if err != nil {
 		return err
@@ -64,8 +78,24 @@ func (c ClusterCreator) Create(options plugin.PluginOptions) error {
 			fmt.Printf("Warning, filename %s is not in the correct format. Skipping\n", f.Name())
 			continue
 		}
+
+		// The node is named by where it was extracted, and older
+		// results without provenance are named by the filename
+		name := nodeName(f.Name(), result)
+		if machine := machineKey(result); machine != "" {
+			existing, ok := machines[machine]
+			if ok {
+				fmt.Printf("Warning, %s is the same machine as %s. Skipping\n", f.Name(), existing)
+				continue
+			}
+			machines[machine] = name
+		}
+		if _, ok := nodes[name]; ok {
+			fmt.Printf("Warning, %s is a second result for node %s. Skipping\n", f.Name(), name)
+			continue
+		}
 		// Add to nodes, if we don't error
-		nodes[f.Name()] = result
+		nodes[name] = result
 	}
 
 	// When we get here, no nodes, no graph
@@ -106,86 +136,59 @@ func (c ClusterCreator) Create(options plugin.PluginOptions) error {
 	// Read in each node and add to the rack.
 	// There are several levels here:
 	// /tiny0/rack0/node0/socket0/core1
-	for nodeFile, meta := range nodes {
+	//
+	// Nodes are added in order of name, so the graph is the same every time
+	names := []string{}
+	for name := range nodes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, nodeID := range names {
+		meta := nodes[nodeID]
 
 		// We must have extractors, nfd, and sections
 		nfd, ok := meta.Results["nfd"]
 		if !ok || len(nfd.Sections) == 0 {
-			fmt.Printf("node %s is missing extractors->nfd data, skipping\n", nodeFile)
+			fmt.Printf("node %s is missing extractors->nfd data, skipping\n", nodeID)
 			continue
 		}
 
 		// We also need system -> sections -> processor
 		system, ok := meta.Results["system"]
 		if !ok || len(system.Sections) == 0 {
-			fmt.Printf("node %s is missing extractors->system data, skipping\n", nodeFile)
+			fmt.Printf("node %s is missing extractors->system data, skipping\n", nodeID)
 			continue
 		}
 		processor, ok := system.Sections["processor"]
 		if !ok || len(processor) == 0 {
-			fmt.Printf("node %s is missing extractors->system->processor, skipping\n", nodeFile)
-			continue
-		}
-		cpu, ok := system.Sections["cpu"]
-		if !ok || len(cpu) == 0 {
-			fmt.Printf("node %s is missing extractors->system->cpu, skipping\n", nodeFile)
+			fmt.Printf("node %s is missing extractors->system->processor, skipping\n", nodeID)
 			continue
 		}
 
-		// IMPORTANT: this is runtime nproces, which might be physical and virtual
-		// we need hwloc for just physical I think
-		cores, ok := cpu["cores"]
-		if !ok {
-			fmt.Printf("node %s is missing extractors->system->cpu->cores, skipping\n", nodeFile)
-			continue
-		}
-		cpuCount, err := strconv.Atoi(cores)
+		// Physical cores for each socket, from the topology when we have it
+		cores, err := socketCores(nfd, system)
 		if err != nil {
-			fmt.Printf("node %s cannot convert cores, skipping\n", nodeFile)
+			fmt.Printf("node %s %s, skipping\n", nodeID, err)
 			continue
 		}
 
-		// First add the rack -> node
+		// First add the rack -> node, named by where it was extracted
 		// We only have one rack here, so hard coded id for now
-		node := *g.AddNode("node", "node", 1, false, "", "rack0")
+		node := *g.AddNamedNode("node", "node", hostName(meta), 1, false, "", "rack0")
 		nodeName, err := node.Metadata.GetStringElement("name")
 
 		// This should not happen, we just added it!
 		if err != nil {
-			fmt.Printf("node %s cannot derive name, skipping\n", nodeFile)
+			fmt.Printf("node %s cannot derive name, skipping\n", nodeID)
 			continue
 		}
 
 		g.AddEdge(rack, node, "contains")
 		g.AddEdge(node, rack, "in")
 
-		// Now add the socket. We need hwloc for this
-		// nfd has a socket count, but we can't be sure which CPU are assigned to which?
-		// This isn't good enough, see https://github.com/compspec/compspec-go/issues/19
-		// For the prototype we will use the nfd socket count and split cores across it
-		// cpu metadata from ndf
-		socketCount := 1
-
-		nfdCpu, ok := nfd.Sections["cpu"]
-		if ok {
-			sockets, ok := nfdCpu["topology.socket_count"]
-			if ok {
-				sCount, err := strconv.Atoi(sockets)
-				if err == nil {
-					socketCount = sCount
-				}
-			}
-		}
-
-		// Get the processors, assume we divide between the sockets
-		// TODO we should also get this in better detail, physical vs logical cores
-		items := []string{}
-		for i := 0; i < cpuCount; i++ {
-			items = append(items, fmt.Sprintf("%d", i))
-		}
-		// Mapping of socket to cores
-		chunks := utils.Chunkify(items, socketCount)
-		for _, chunk := range chunks {
+		// Mapping of socket to cores, and the number of gpus for each socket
+		gpus := gpuSockets(meta, len(cores))
+		for i, count := range cores {
 
 			// Create each socket attached to the node
 			// rack -> node -> socket
@@ -195,7 +198,7 @@ func (c ClusterCreator) Create(options plugin.PluginOptions) error {
 
 			// This also is not going to happen
 			if err != nil {
-				fmt.Printf("socket %x cannot derive name, skipping\n", socketNode)
+				fmt.Printf("socket %s cannot derive name, skipping\n", *socketNode.Label)
 				continue
 			}
 
@@ -203,13 +206,21 @@ func (c ClusterCreator) Create(options plugin.PluginOptions) error {
 			g.AddEdge(socketNode, node, "in")
 
 			// Create each core attached to the socket
-			for range chunk {
+			for j := 0; j < count; j++ {
 				path := fmt.Sprintf("rack0/%s/%s", nodeName, socketName)
 				coreNode := *g.AddNode("core", "core", 1, false, "", path)
 				g.AddEdge(socketNode, coreNode, "contains")
 				g.AddEdge(coreNode, socketNode, "in")
 
 			}
+
+			// And each gpu, which (like a core) is in a socket for fluxion
+			for j := 0; j < gpus[i]; j++ {
+				path := fmt.Sprintf("rack0/%s/%s", nodeName, socketName)
+				gpuNode := *g.AddNode("gpu", "gpu", 1, false, "", path)
+				g.AddEdge(socketNode, gpuNode, "contains")
+				g.AddEdge(gpuNode, socketNode, "in")
+			}
 		}
 	}
 
@@ -228,12 +239,153 @@ func (c ClusterCreator) Create(options plugin.PluginOptions) error {
 
 }
 
-func (c ClusterCreator) Validate() bool {
-	return true
+// socketCores returns the number of cores in each socket. The topology
+// section knows the physical cores of each socket. Without it, we use
+// the nfd socket count and split the logical processors across it.
+// This isn't good enough, see https://github.com/compspec/compspec-go/issues/19
+func socketCores(nfd plugin.PluginData, system plugin.PluginData) ([]int, error) {
+	topology := system.Sections["topology"]
+	sockets := []int{}
+	for key := range topology {
+		match := regexSocketCores.FindStringSubmatch(key)
+		if match == nil {
+			continue
+		}
+		socket, err := strconv.Atoi(match[1])
+		if err == nil {
+			sockets = append(sockets, socket)
+		}
+	}
+	if len(sockets) > 0 {
+		sort.Ints(sockets)
+		cores := []int{}
+		for _, socket := range sockets {
+			count, ok := topology[fmt.Sprintf("socket.%d.cores", socket)].Int()
+			if !ok {
+				return nil, fmt.Errorf("cannot convert extractors->system->topology cores")
+			}
+			cores = append(cores, int(count))
+		}
+		return cores, nil
+	}
+
+	cpu, ok := system.Sections["cpu"]
+	if !ok || len(cpu) == 0 {
+		return nil, fmt.Errorf("is missing extractors->system->cpu")
+	}
+
+	// IMPORTANT: this is runtime nproces, which might be physical and virtual
+	count, ok := cpu["cores"]
+	if !ok {
+		return nil, fmt.Errorf("is missing extractors->system->cpu->cores")
+	}
+	cpuCount, ok := count.Int()
+	if !ok {
+		return nil, fmt.Errorf("cannot convert cores")
+	}
+	socketCount := 1
+	nfdCpu, ok := nfd.Sections["cpu"]
+	if ok {
+		sockets, ok := nfdCpu["topology.socket_count"]
+		if ok {
+			sCount, ok := sockets.Int()
+			if ok {
+				socketCount = int(sCount)
+			}
+		}
+	}
+
+	// Assume we divide the processors between the sockets
+	items := []string{}
+	for i := 0; i < int(cpuCount); i++ {
+		items = append(items, fmt.Sprintf("%d", i))
+	}
+	cores := []int{}
+	for _, chunk := range utils.Chunkify(items, socketCount) {
+		cores = append(cores, len(chunk))
+	}
+	return cores, nil
+}
+
+// gpuSockets returns the number of gpus in each socket, from the gpu
+// extractor. We don't know the socket of a gpu, so the numa node is
+// our best guess, and a gpu without one is in the first socket.
+// Integrated graphics is not a gpu for the graph.
+func gpuSockets(meta plugin.Result, sockets int) []int {
+	counts := make([]int, sockets)
+	if sockets == 0 {
+		return counts
+	}
+	devices := meta.Results["gpu"].Sections["devices"]
+	for i := 0; ; i++ {
+		if _, ok := devices[fmt.Sprintf("%d.address", i)]; !ok {
+			break
+		}
+		if integrated, _ := devices[fmt.Sprintf("%d.integrated", i)].Bool(); integrated {
+			continue
+		}
+		socket := 0
+		numaNode, ok := devices[fmt.Sprintf("%d.numa_node", i)].Int()
+		if ok && numaNode > 0 {
+			socket = int(numaNode) % sockets
+		}
+		counts[socket] += 1
+	}
+	return counts
+}
+
+// nodeName identifies a node by its provenance, or by the filename
+func nodeName(filename string, result plugin.Result) string {
+	name := hostName(result)
+	if name == "" {
+		return filename
+	}
+	return name
+}
+
+// hostName is the name of the node from its provenance (the hostname, or
+// the machine id), which is empty if it is not known. A masked field
+// cannot identify a node, but a hashed one can.
+func hostName(result plugin.Result) string {
+	if result.Provenance == nil {
+		return ""
+	}
+	fields := [][2]string{
+		{"provenance.hostname", result.Provenance.Hostname},
+		{"provenance.machineId", result.Provenance.MachineID},
+	}
+	for _, field := range fields {
+		if known(result, field[0], field[1]) {
+			return field[1]
+		}
+	}
+	return ""
+}
+
+// machineKey identifies the machine a result is from, or is empty if we
+// can't tell. In a container, the machine id is from the image (and can
+// be shared by many nodes), so the hostname and boot id must match too.
+func machineKey(result plugin.Result) string {
+	if result.Provenance == nil || !known(result, "provenance.machineId", result.Provenance.MachineID) {
+		return ""
+	}
+	p := result.Provenance
+	if p.Container == "" {
+		return p.MachineID
+	}
+	if !known(result, "provenance.hostname", p.Hostname) || !known(result, "provenance.bootId", p.BootID) {
+		return ""
+	}
+	return strings.Join([]string{p.MachineID, p.Hostname, p.BootID}, "/")
+}
+
+// known determines if a provenance field has a value that is not masked
+func known(result plugin.Result, field, value string) bool {
+	return value != "" && result.Redacted[field] != string(redact.Mask)
 }
 
 // NewPlugin creates a new ClusterCreator
-func NewPlugin() (plugin.PluginInterface, error) {
+func NewPlugin() (plugin.Creator, error) {
 	c := ClusterCreator{}
 	return c, nil
 }
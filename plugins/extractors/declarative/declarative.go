@@ -0,0 +1,130 @@
+package declarative
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/compspec/compspec-go/pkg/plugin"
+	"github.com/compspec/compspec-go/pkg/utils"
+)
+
+// DeclarativeExtractor extracts metadata according to a YAML definition
+type DeclarativeExtractor struct {
+	definition *Definition
+	sections   []string
+}
+
+func (e DeclarativeExtractor) Name() string {
+	return e.definition.Name
+}
+
+func (e DeclarativeExtractor) Description() string {
+	return e.definition.Description
+}
+
+// Version is the version of the definition, if any
+func (e DeclarativeExtractor) Version() string {
+	return e.definition.Version
+}
+
+func (e DeclarativeExtractor) Sections() []string {
+	return e.sections
+}
+
+// Validate ensures that the sections provided are in the list we know
+func (e DeclarativeExtractor) Validate() bool {
+	invalids, valid := utils.StringArrayIsSubset(e.sections, e.definition.SectionNames())
+	for _, invalid := range invalids {
+		fmt.Fprintf(os.Stderr, "Sections %s is not known for extractor plugin %s\n", invalid, e.Name())
+	}
+	return valid
+}
+
+// Extract returns metadata, for a set of named sections
+func (e DeclarativeExtractor) Extract(ctx context.Context, allowFail bool) (plugin.PluginData, error) {
+	return plugin.ExtractSections(ctx, e, e.sections, allowFail)
+}
+
+// ExtractSection applies the rules for a single named section
+func (e DeclarativeExtractor) ExtractSection(ctx context.Context, name string) (plugin.PluginSection, error) {
+	for _, section := range e.definition.Sections {
+		if section.Name != name {
+			continue
+		}
+		data := plugin.PluginSection{}
+		for _, rule := range section.Rules {
+			err := rule.apply(ctx, data)
+
+			// Fields from earlier rules are kept
+			if err != nil && len(data) > 0 {
+				return data, plugin.Partial(err)
+			}
+			if err != nil {
+				return data, err
+			}
+		}
+		return data, nil
+	}
+	return nil, fmt.Errorf("section %s is not known for extractor plugin %s", name, e.Name())
+}
+
+// Source is the definition file for the extractor
+func (e DeclarativeExtractor) Source(name string) string {
+	return e.definition.path
+}
+
+// Fields describes the fields that the rules of each section return
+func (e DeclarativeExtractor) Fields() []plugin.FieldSpec {
+	fields := []plugin.FieldSpec{}
+	for _, section := range e.definition.Sections {
+		for i := range section.Rules {
+			fields = append(fields, section.Rules[i].fields(section.Name)...)
+		}
+	}
+	return fields
+}
+
+// NewPlugin validates and returns a new plugin for a definition
+func NewPlugin(definition *Definition, sections []string) (plugin.Plugin, error) {
+	if len(sections) == 0 {
+		sections = definition.SectionNames()
+	}
+	e := DeclarativeExtractor{definition: definition, sections: sections}
+	if !e.Validate() {
+		return nil, fmt.Errorf("plugin %s is not valid", e.Name())
+	}
+	return e, nil
+}
+
+// Register loads definitions (*.yaml or *.yml) from plugin directories
+// and registers them as plugins. The first definition for a name is used,
+// and plugins that are already registered take precedence.
+func Register(pluginDirs []string) error {
+	for _, dir := range plugin.PluginDirs(pluginDirs) {
+		definitions, err := LoadDir(dir)
+		if err != nil {
+			return err
+		}
+		for _, definition := range definitions {
+			_, err := plugin.Lookup(definition.Name)
+			if err == nil {
+				fmt.Fprintf(os.Stderr, "warning: extractor definition %s has the same name as a known plugin, skipping\n", definition.path)
+				continue
+			}
+
+			// Assign to a new variable for the closure
+			definition := definition
+			plugin.Register(plugin.Registration{
+				Name:        definition.Name,
+				Description: definition.Description,
+				Kind:        plugin.ExtractorKind,
+				Sections:    definition.SectionNames(),
+				New: func(sections []string) (plugin.Plugin, error) {
+					return NewPlugin(definition, sections)
+				},
+			})
+		}
+	}
+	return nil
+}
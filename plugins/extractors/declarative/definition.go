@@ -0,0 +1,203 @@
+package declarative
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/compspec/compspec-go/pkg/plugin"
+	"sigs.k8s.io/yaml"
+)
+
+// A Definition describes an extractor in YAML, for example:
+//
+//	name: lustre
+//	description: lustre client
+//	version: 1.0.0
+//	sections:
+//	- name: client
+//	  rules:
+//	  - file: /sys/fs/lustre/version
+//	    regex: 'lustre: (\S+)'
+//	    field: version
+//	    description: lustre client version
+type Definition struct {
+	Name        string    `json:"name"`
+	Description string    `json:"description,omitempty"`
+	Version     string    `json:"version,omitempty"`
+	Sections    []Section `json:"sections"`
+
+	// The file the definition was loaded from
+	path string
+}
+
+// A Section is a named group of rules
+type Section struct {
+	Name  string `json:"name"`
+	Rules []Rule `json:"rules"`
+}
+
+// A Rule reads content from one source (a file, a glob of files, or
+// a command) and parses it into one or more fields.
+type Rule struct {
+	File    string   `json:"file,omitempty"`
+	Glob    string   `json:"glob,omitempty"`
+	Command []string `json:"command,omitempty"`
+
+	// A regular expression to match. Named groups become fields (prefixed
+	// by field, if defined) and otherwise the first group is the field value.
+	Regex string `json:"regex,omitempty"`
+
+	// A delimiter to split each line into a key and value (prefixed by
+	// field, if defined), skipping lines that start with the comment
+	Split   string `json:"split,omitempty"`
+	Comment string `json:"comment,omitempty"`
+
+	// Characters to trim from each value (e.g., quotes), after whitespace
+	Trim string `json:"trim,omitempty"`
+
+	// The type of each value (e.g., int or bytes), defaulting to string
+	Type plugin.ValueType `json:"type,omitempty"`
+
+	// The field name (or prefix). For a glob, {1}, {2}, etc. are replaced
+	// by the path component matched by each wildcard.
+	Field string `json:"field,omitempty"`
+
+	// If the source does not exist, skip the rule instead of failing
+	Optional bool `json:"optional,omitempty"`
+
+	// A description and unit of the fields, for the field catalog
+	Description string `json:"description,omitempty"`
+	Unit        string `json:"unit,omitempty"`
+
+	regex *regexp.Regexp
+}
+
+// Load reads and validates a definition from a YAML file
+func Load(path string) (*Definition, error) {
+	d := Definition{path: path}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	err = yaml.UnmarshalStrict(content, &d)
+	if err != nil {
+		return nil, fmt.Errorf("%s is not a valid extractor definition: %s", path, err)
+	}
+	return &d, d.validate()
+}
+
+// LoadDir loads all definitions (*.yaml or *.yml) in a directory. A
+// definition that is not valid is skipped (with a warning), so one bad
+// file does not stop compspec from running.
+func LoadDir(dir string) ([]*Definition, error) {
+	definitions := []*Definition{}
+	for _, pattern := range []string{"*.yaml", "*.yml"} {
+		paths, err := filepath.Glob(filepath.Join(dir, pattern))
+		if err != nil {
+			return definitions, err
+		}
+		for _, path := range paths {
+			d, err := Load(path)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "warning: %s, skipping\n", err)
+				continue
+			}
+			definitions = append(definitions, d)
+		}
+	}
+	return definitions, nil
+}
+
+// SectionNames returns the names of sections, in the order they are defined
+func (d *Definition) SectionNames() []string {
+	names := []string{}
+	for _, section := range d.Sections {
+		names = append(names, section.Name)
+	}
+	return names
+}
+
+// validate checks the definition and compiles regular expressions
+func (d *Definition) validate() error {
+	if d.Name == "" {
+		return fmt.Errorf("%s is missing a name", d.path)
+	}
+	if strings.ContainsAny(d.Name, plugin.NameSeparators) {
+		return fmt.Errorf("%s name %s can't have any of %q", d.path, d.Name, plugin.NameSeparators)
+	}
+	if len(d.Sections) == 0 {
+		return fmt.Errorf("%s does not define any sections", d.path)
+	}
+	seen := map[string]bool{}
+	for i, section := range d.Sections {
+		if section.Name == "" {
+			return fmt.Errorf("%s section %d is missing a name", d.path, i)
+		}
+		if strings.ContainsAny(section.Name, plugin.NameSeparators) {
+			return fmt.Errorf("%s section %s can't have any of %q", d.path, section.Name, plugin.NameSeparators)
+		}
+		if seen[section.Name] {
+			return fmt.Errorf("%s section %s is defined twice", d.path, section.Name)
+		}
+		seen[section.Name] = true
+
+		for j := range section.Rules {
+			err := section.Rules[j].validate()
+			if err != nil {
+				return fmt.Errorf("%s section %s rule %d: %s", d.path, section.Name, j, err)
+			}
+		}
+	}
+	return nil
+}
+
+// validate checks a single rule
+func (r *Rule) validate() error {
+	sources := 0
+	for _, defined := range []bool{r.File != "", r.Glob != "", len(r.Command) > 0} {
+		if defined {
+			sources += 1
+		}
+	}
+	if sources != 1 {
+		return fmt.Errorf("exactly one of file, glob, or command is required")
+	}
+	if r.Regex != "" && r.Split != "" {
+		return fmt.Errorf("only one of regex or split can be used")
+	}
+	if r.Regex != "" {
+		regex, err := regexp.Compile(r.Regex)
+		if err != nil {
+			return err
+		}
+		r.regex = regex
+
+		// Without named groups, we need a field name for the value
+		if r.Field == "" && !hasNamedGroups(regex) {
+			return fmt.Errorf("a field is required for a regex without named groups")
+		}
+	}
+
+	if !r.Type.IsKnown() {
+		return fmt.Errorf("%s is not a known value type", r.Type)
+	}
+
+	// The entire content needs a field name
+	if r.Regex == "" && r.Split == "" && r.Field == "" {
+		return fmt.Errorf("a field is required without a regex or split")
+	}
+	return nil
+}
+
+// hasNamedGroups determines if a regular expression has named groups
+func hasNamedGroups(regex *regexp.Regexp) bool {
+	for _, name := range regex.SubexpNames() {
+		if name != "" {
+			return true
+		}
+	}
+	return false
+}
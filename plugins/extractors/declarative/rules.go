@@ -0,0 +1,185 @@
+package declarative
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/compspec/compspec-go/pkg/plugin"
+	"github.com/compspec/compspec-go/pkg/rootfs"
+	"github.com/compspec/compspec-go/pkg/utils"
+)
+
+// wildcards are characters in a glob pattern that can match
+const wildcards = "*?["
+
+// regexGlobField matches {1}, {2}, etc. in a field for a glob
+var regexGlobField = regexp.MustCompile(`\{\d+\}`)
+
+// apply runs a rule, adding fields to the section. Files are read from
+// the root in the context, which is the host unless it is set.
+func (r *Rule) apply(ctx context.Context, section plugin.PluginSection) error {
+	root := rootfs.FromContext(ctx)
+
+	// A file is read as is
+	if r.File != "" {
+		content, err := root.ReadFile(r.File)
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) && r.Optional {
+				return nil
+			}
+			return err
+		}
+		r.parse(string(content), r.Field, section)
+		return nil
+	}
+
+	// A command is run, and the output parsed. It would run on the host,
+	// so we can't run it for another root.
+	if len(r.Command) > 0 {
+		if !root.IsHost() && r.Optional {
+			return nil
+		}
+		if !root.IsHost() {
+			return plugin.Unsupported("%s cannot be run for root %s", r.Command[0], root)
+		}
+		_, err := exec.LookPath(r.Command[0])
+		if err != nil && r.Optional {
+			return nil
+		}
+		output, err := utils.RunCommandContext(ctx, r.Command)
+		if err != nil {
+			return fmt.Errorf("%s: %s", strings.Join(r.Command, " "), err)
+		}
+		r.parse(output, r.Field, section)
+		return nil
+	}
+
+	// Each path in a glob is a file that fills in the field name
+	paths, err := root.Glob(r.Glob)
+	if err != nil {
+		return err
+	}
+	if len(paths) == 0 && !r.Optional {
+		return fmt.Errorf("no files match %s", r.Glob)
+	}
+	for _, path := range paths {
+		content, err := root.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		r.parse(string(content), globField(r.Field, r.Glob, path), section)
+	}
+	return nil
+}
+
+// parse content into fields, according to the regex or split
+func (r *Rule) parse(content, field string, section plugin.PluginSection) {
+
+	// Key value pairs, one per line
+	if r.Split != "" {
+		for _, line := range strings.Split(content, "\n") {
+			line = strings.TrimSpace(line)
+			if line == "" || (r.Comment != "" && strings.HasPrefix(line, r.Comment)) {
+				continue
+			}
+			parts := strings.SplitN(line, r.Split, 2)
+			if len(parts) < 2 {
+				continue
+			}
+			key := strings.TrimSpace(parts[0])
+			section[joinField(field, key)] = r.value(parts[1])
+		}
+		return
+	}
+
+	// The entire value
+	if r.regex == nil {
+		section[field] = r.value(content)
+		return
+	}
+
+	// No match means no fields
+	match := r.regex.FindStringSubmatch(content)
+	if match == nil {
+		return
+	}
+	if !hasNamedGroups(r.regex) {
+		value := match[0]
+		if len(match) > 1 {
+			value = match[1]
+		}
+		section[field] = r.value(value)
+		return
+	}
+	for i, name := range r.regex.SubexpNames() {
+		if name != "" {
+			section[joinField(field, name)] = r.value(match[i])
+		}
+	}
+}
+
+// value cleans up a value, trimming whitespace and any extra characters,
+// and parses it as the rule type. A value that cannot be parsed is kept
+// as a string so the field is not lost.
+func (r *Rule) value(value string) plugin.Value {
+	value = strings.Trim(strings.TrimSpace(value), r.Trim)
+	typed, err := plugin.ParseValue(value, r.Type)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: cannot parse %q as %s, keeping as a string\n", value, r.Type)
+		return plugin.String(value)
+	}
+	return typed
+}
+
+// joinField adds an optional prefix to a field name
+func joinField(prefix, name string) string {
+	if prefix == "" {
+		return name
+	}
+	return prefix + "." + name
+}
+
+// globField replaces {1}, {2}, etc. in a field with the path
+// components that matched each wildcard in the glob pattern
+func globField(field, pattern, path string) string {
+	patternParts := strings.Split(filepath.Clean(pattern), string(filepath.Separator))
+	pathParts := strings.Split(filepath.Clean(path), string(filepath.Separator))
+
+	count := 0
+	for i, part := range patternParts {
+		if !strings.ContainsAny(part, wildcards) || i >= len(pathParts) {
+			continue
+		}
+		count += 1
+		field = strings.ReplaceAll(field, fmt.Sprintf("{%d}", count), pathParts[i])
+	}
+	return field
+}
+
+// fields describes the fields that a rule returns for the field catalog,
+// where a part that depends on what is found is a *
+func (r *Rule) fields(section string) []plugin.FieldSpec {
+	field := regexGlobField.ReplaceAllString(r.Field, "*")
+	names := []string{field}
+	if r.Split != "" {
+		names = []string{joinField(field, "*")}
+	} else if r.regex != nil && hasNamedGroups(r.regex) {
+		names = []string{}
+		for _, name := range r.regex.SubexpNames() {
+			if name != "" {
+				names = append(names, joinField(field, name))
+			}
+		}
+	}
+	specs := []plugin.FieldSpec{}
+	for _, name := range names {
+		specs = append(specs, plugin.FieldSpec{Section: section, Name: name, Type: r.Type, Unit: r.Unit, Description: r.Description})
+	}
+	return specs
+}
@@ -0,0 +1,273 @@
+package external
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/compspec/compspec-go/pkg/plugin"
+	"github.com/compspec/compspec-go/pkg/rootfs"
+	"github.com/compspec/compspec-go/pkg/utils"
+)
+
+// External extractors are executables named compspec-extractor-<name> that
+// speak a small JSON protocol over stdout:
+//
+//	compspec-extractor-<name> describe
+//	  {"name": "<name>", "description": "...", "sections": ["a", "b"], "version": "1.0.0"}
+//
+// The description can also have "fields" for the field catalog, each with
+// a section, name, and (optionally) type, unit, description, and example.
+//
+//	compspec-extractor-<name> extract [--allow-fail] [section...]
+//	  {"sections": {"a": {"key": "value"}}}
+//
+// No sections for extract means all sections. A non-zero exit code is an
+// error, and anything written to stderr is included in the message. When
+// extracting for a root that is not the host, it is in COMPSPEC_ROOT.
+const (
+	ExecutablePrefix = "compspec-extractor-"
+	RootEnv          = "COMPSPEC_ROOT"
+
+	DescribeCommand = "describe"
+	ExtractCommand  = "extract"
+	AllowFailFlag   = "--allow-fail"
+)
+
+var (
+	// How long we wait for an extractor to describe itself
+	describeTimeout = 10 * time.Second
+
+	// Descriptions by path, so an executable is described once
+	descriptionsMutex sync.Mutex
+	descriptions      = map[string]Description{}
+)
+
+// Description is what an external extractor returns to describe itself
+type Description struct {
+	Name        string   `json:"name,omitempty"`
+	Description string   `json:"description,omitempty"`
+	Sections    []string `json:"sections,omitempty"`
+	Version     string   `json:"version,omitempty"`
+
+	// Fields that the extractor returns, if it describes them
+	Fields []plugin.FieldSpec `json:"fields,omitempty"`
+}
+
+// ExternalExtractor runs an executable to extract metadata
+type ExternalExtractor struct {
+	name        string
+	path        string
+	description Description
+	sections    []string
+}
+
+func (e ExternalExtractor) Name() string {
+	return e.name
+}
+
+func (e ExternalExtractor) Description() string {
+	return e.description.Description
+}
+
+// Version is the version that the executable describes, if any
+func (e ExternalExtractor) Version() string {
+	return e.description.Version
+}
+
+func (e ExternalExtractor) Sections() []string {
+	return e.sections
+}
+
+// Fields are the fields that the executable describes, if any
+func (e ExternalExtractor) Fields() []plugin.FieldSpec {
+	return e.description.Fields
+}
+
+// Path is the full path to the executable
+func (e ExternalExtractor) Path() string {
+	return e.path
+}
+
+// Validate ensures that the sections provided are in the list we know
+func (e ExternalExtractor) Validate() bool {
+	invalids, valid := utils.StringArrayIsSubset(e.sections, e.description.Sections)
+	for _, invalid := range invalids {
+		fmt.Fprintf(os.Stderr, "Sections %s is not known for extractor plugin %s\n", invalid, e.Name())
+	}
+	return valid
+}
+
+// Extract runs the executable once for all requested sections. This is
+// why an external extractor is not a SectionExtractor, which is run once
+// for each section.
+func (e ExternalExtractor) Extract(ctx context.Context, allowFail bool) (plugin.PluginData, error) {
+	args := []string{ExtractCommand}
+	if allowFail {
+		args = append(args, AllowFailFlag)
+	}
+	args = append(args, e.sections...)
+	return e.extract(ctx, args)
+}
+
+// Source is the executable that extracts all sections
+func (e ExternalExtractor) Source(name string) string {
+	return e.path
+}
+
+// extract runs the executable with arguments and parses plugin data
+func (e ExternalExtractor) extract(ctx context.Context, args []string) (plugin.PluginData, error) {
+	data := plugin.PluginData{}
+	env := []string{}
+	if root := rootfs.FromContext(ctx); !root.IsHost() {
+		env = append(env, fmt.Sprintf("%s=%s", RootEnv, root))
+	}
+	output, err := run(ctx, e.path, args, env)
+	if err != nil {
+		return data, err
+	}
+	err = json.Unmarshal(output, &data)
+	if err != nil {
+		return data, fmt.Errorf("%s did not return valid extractor data: %s", e.path, err)
+	}
+	return data, nil
+}
+
+// run runs an external extractor, including stderr in any error
+func run(ctx context.Context, path string, args []string, env []string) ([]byte, error) {
+	output, err := utils.RunCommandEnv(ctx, append([]string{path}, args...), env)
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+			return nil, fmt.Errorf("%s %s: %s: %s", filepath.Base(path), args[0], err, strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return nil, fmt.Errorf("%s %s: %s", filepath.Base(path), args[0], err)
+	}
+	return []byte(output), nil
+}
+
+// describe asks an external extractor to describe itself
+func describe(name, path string) (Description, error) {
+	description := Description{}
+
+	ctx, cancel := context.WithTimeout(context.Background(), describeTimeout)
+	defer cancel()
+
+	output, err := run(ctx, path, []string{DescribeCommand}, nil)
+	if err != nil {
+		return description, err
+	}
+	err = json.Unmarshal(output, &description)
+	if err != nil {
+		return description, fmt.Errorf("%s did not return a valid description: %s", path, err)
+	}
+	if description.Name != "" && description.Name != name {
+		return description, fmt.Errorf("%s describes itself as %s, expected %s", path, description.Name, name)
+	}
+	if len(description.Sections) == 0 {
+		return description, fmt.Errorf("%s does not describe any sections", path)
+	}
+	descriptionsMutex.Lock()
+	defer descriptionsMutex.Unlock()
+	descriptions[path] = description
+	return description, nil
+}
+
+// cached returns the extractor (for all sections) if it has been described,
+// so a result can be typed (or checked) without running the executable
+func cached(name, path string) (plugin.Plugin, bool) {
+	descriptionsMutex.Lock()
+	defer descriptionsMutex.Unlock()
+	description, ok := descriptions[path]
+	if !ok {
+		return nil, false
+	}
+	return ExternalExtractor{name: name, path: path, description: description, sections: description.Sections}, true
+}
+
+// NewPlugin describes the executable and returns a new plugin
+func NewPlugin(name, path string, sections []string) (plugin.Plugin, error) {
+	description, err := describe(name, path)
+	if err != nil {
+		return nil, err
+	}
+	if len(sections) == 0 {
+		sections = description.Sections
+	}
+	e := ExternalExtractor{name: name, path: path, description: description, sections: sections}
+	if !e.Validate() {
+		return nil, fmt.Errorf("plugin %s is not valid", e.Name())
+	}
+	return e, nil
+}
+
+// Find returns a lookup of external extractor names to executables.
+// The plugin directories are searched first, then COMPSPEC_PLUGIN_PATH,
+// and then PATH, and the first executable found for a name is used.
+func Find(pluginDirs []string) map[string]string {
+	dirs := plugin.PluginDirs(pluginDirs)
+	dirs = append(dirs, filepath.SplitList(os.Getenv("PATH"))...)
+
+	found := map[string]string{}
+	for _, dir := range dirs {
+		if dir == "" {
+			continue
+		}
+		matches, err := filepath.Glob(filepath.Join(dir, ExecutablePrefix+"*"))
+		if err != nil {
+			continue
+		}
+		for _, path := range matches {
+			name := strings.TrimPrefix(filepath.Base(path), ExecutablePrefix)
+			if _, ok := found[name]; ok || name == "" {
+				continue
+			}
+			info, err := os.Stat(path)
+			if err != nil || info.IsDir() || info.Mode()&0111 == 0 {
+				continue
+			}
+
+			// A name with a separator (e.g., foo.py) can't address its fields
+			if strings.ContainsAny(name, plugin.NameSeparators) {
+				fmt.Fprintf(os.Stderr, "warning: external extractor %s has a name with one of %q, skipping\n", path, plugin.NameSeparators)
+				continue
+			}
+			found[name] = path
+		}
+	}
+	return found
+}
+
+// Register finds external extractors and registers them as plugins.
+// An executable is not run until the plugin is requested, and plugins
+// that are compiled in take precedence over an executable of the same name.
+func Register(pluginDirs []string) {
+	for name, path := range Find(pluginDirs) {
+		_, err := plugin.Lookup(name)
+		if err == nil {
+			fmt.Fprintf(os.Stderr, "warning: external extractor %s has the same name as a known plugin, skipping\n", path)
+			continue
+		}
+
+		// Assign to new variables for the closure
+		name, path := name, path
+		plugin.Register(plugin.Registration{
+			Name:        name,
+			Description: fmt.Sprintf("external extractor %s", path),
+			Kind:        plugin.ExtractorKind,
+			New: func(sections []string) (plugin.Plugin, error) {
+				return NewPlugin(name, path, sections)
+			},
+			Cached: func() (plugin.Plugin, bool) {
+				return cached(name, path)
+			},
+		})
+	}
+}
@@ -0,0 +1,219 @@
+package gpu
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/compspec/compspec-go/pkg/plugin"
+	"github.com/compspec/compspec-go/pkg/rootfs"
+)
+
+// Locations to find gpus and their drivers
+const (
+	pciDevices = "/sys/bus/pci/devices"
+
+	// Versions of kernel modules (drivers), when they have one
+	moduleDir = "/sys/module"
+
+	// The nvidia driver has its own files for the version and each gpu
+	nvidiaVersionFile = "/proc/driver/nvidia/version"
+	nvidiaGPUsDir     = "/proc/driver/nvidia/gpus"
+
+	// Display controllers are pci class 0x03 (vga, 3d, and other)
+	displayClassPrefix = "0x03"
+)
+
+var (
+	// Vendors that we know make gpus, by pci vendor id. Other display
+	// controllers (e.g., the vga of a BMC) are not gpus.
+	vendors = map[string]string{
+		"0x10de": "nvidia",
+		"0x1002": "amd",
+		"0x8086": "intel",
+	}
+
+	// Intel discrete gpus by device id: DG1 (0x4905-0x4909), Arc and Flex
+	// (0x56xx), Data Center Max (0x0bdx), and Battlemage (0xe2xx). Other
+	// Intel display controllers are integrated graphics. We can't tell by
+	// the bus, since a discrete gpu passed through to a vm is often on the
+	// root bus (e.g., 0000:00:05.0) like integrated graphics.
+	regexIntelDiscrete = regexp.MustCompile(`^0x(490[5-9]|56[0-9a-f]{2}|0bd[0-9a-f]|e2[0-9a-f]{2})$`)
+
+	// NVRM version: NVIDIA UNIX x86_64 Kernel Module  535.104.05  Sat Aug 19 01:15:15 UTC 2023
+	regexNvidiaVersion = regexp.MustCompile(`Kernel Module(?: for \S+)?\s+(\d+(?:\.\d+)+)`)
+	regexNvidiaModel   = regexp.MustCompile(`(?m)^Model:\s*(.+)$`)
+)
+
+// A Device is a gpu on the pci bus
+type Device struct {
+	Address  string
+	Vendor   string
+	VendorID string
+	DeviceID string
+	Class    string
+	Driver   string
+	NumaNode string
+	Memory   string
+	Model    string
+
+	// Integrated graphics (Intel) are reported, but are not counted as gpus
+	Integrated bool
+}
+
+// getDevices finds gpus on the pci bus, sorted by address
+func getDevices(root rootfs.Root) ([]Device, error) {
+	entries, err := root.ReadDir(pciDevices)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, plugin.Skipped("%s is not found", pciDevices)
+	}
+	if err != nil {
+		return nil, err
+	}
+	devices := []Device{}
+	for _, entry := range entries {
+		path := filepath.Join(pciDevices, entry.Name())
+		class := readAttribute(root, path, "class")
+		if !strings.HasPrefix(class, displayClassPrefix) {
+			continue
+		}
+		vendorID := readAttribute(root, path, "vendor")
+		vendor, ok := vendors[vendorID]
+		if !ok {
+			continue
+		}
+		deviceID := readAttribute(root, path, "device")
+		device := Device{
+			Address:  entry.Name(),
+			Vendor:   vendor,
+			VendorID: vendorID,
+			DeviceID: deviceID,
+			Class:    class,
+			NumaNode: readAttribute(root, path, "numa_node"),
+
+			// Only amdgpu exposes the memory of the device here
+			Memory:     readAttribute(root, path, "mem_info_vram_total"),
+			Integrated: vendor == "intel" && !regexIntelDiscrete.MatchString(deviceID),
+		}
+		driver, err := root.Readlink(filepath.Join(path, "driver"))
+		if err == nil {
+			device.Driver = filepath.Base(driver)
+		}
+
+		// The nvidia driver describes each gpu, including the model
+		raw, err := root.ReadFile(filepath.Join(nvidiaGPUsDir, device.Address, "information"))
+		if err == nil {
+			match := regexNvidiaModel.FindStringSubmatch(string(raw))
+			if match != nil {
+				device.Model = strings.TrimSpace(match[1])
+			}
+		}
+		devices = append(devices, device)
+	}
+	sort.Slice(devices, func(i, j int) bool { return devices[i].Address < devices[j].Address })
+	return devices, nil
+}
+
+// readAttribute reads a (one line) attribute of a device, which is empty
+// if the device does not have it
+func readAttribute(root rootfs.Root, path, name string) string {
+	raw, err := root.ReadFile(filepath.Join(path, name))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(raw))
+}
+
+// getDevicesInformation returns fields for each device, by index
+func getDevicesInformation(devices []Device) (plugin.PluginSection, error) {
+	if len(devices) == 0 {
+		return nil, plugin.Skipped("no gpus were found")
+	}
+	info := plugin.PluginSection{}
+	for i, device := range devices {
+		uid := fmt.Sprintf("%d.", i)
+		info[uid+"address"] = plugin.String(device.Address)
+		info[uid+"vendor"] = plugin.String(device.Vendor)
+		info[uid+"vendor_id"] = plugin.String(device.VendorID)
+		info[uid+"device_id"] = plugin.String(device.DeviceID)
+		info[uid+"class"] = plugin.String(device.Class)
+		info[uid+"integrated"] = plugin.Bool(device.Integrated)
+		if device.Driver != "" {
+			info[uid+"driver"] = plugin.String(device.Driver)
+		}
+		if numaNode, err := strconv.ParseInt(device.NumaNode, 10, 64); err == nil {
+			info[uid+"numa_node"] = plugin.Int(numaNode)
+		}
+		if memory, err := strconv.ParseInt(device.Memory, 10, 64); err == nil {
+			info[uid+"memory"] = plugin.Bytes(memory, "B")
+		}
+		if device.Model != "" {
+			info[uid+"model"] = plugin.String(device.Model)
+		}
+	}
+	return info, nil
+}
+
+// getDriversInformation returns the version of each driver for the devices.
+// The nvidia driver has its own version file, and for others we use the
+// version of the kernel module, when it has one (e.g., a dkms build).
+func getDriversInformation(root rootfs.Root, devices []Device) (plugin.PluginSection, error) {
+	info := plugin.PluginSection{}
+	for _, device := range devices {
+		if device.Driver == "" {
+			continue
+		}
+		if _, ok := info[device.Driver+".version"]; ok {
+			continue
+		}
+		version := ""
+		if device.Driver == "nvidia" {
+			raw, err := root.ReadFile(nvidiaVersionFile)
+			if err == nil {
+				match := regexNvidiaVersion.FindStringSubmatch(string(raw))
+				if match != nil {
+					version = match[1]
+				}
+			}
+		} else {
+			version = readAttribute(root, filepath.Join(moduleDir, device.Driver), "version")
+		}
+		if version != "" {
+			info[device.Driver+".version"] = plugin.Version(version)
+		}
+	}
+	if len(info) == 0 {
+		return nil, plugin.Skipped("no gpu driver versions were found")
+	}
+	return info, nil
+}
+
+// getSummary counts the gpus (not integrated graphics), in total and for
+// each vendor
+func getSummary(devices []Device) plugin.PluginSection {
+	info := plugin.PluginSection{}
+	counts := map[string]int64{}
+	count := int64(0)
+	for _, device := range devices {
+		if device.Integrated {
+			continue
+		}
+		counts[device.Vendor] += 1
+		count += 1
+	}
+	names := []string{}
+	for vendor, count := range counts {
+		info[vendor+".count"] = plugin.Int(count)
+		names = append(names, vendor)
+	}
+	sort.Strings(names)
+	info["available"] = plugin.Bool(count > 0)
+	info["count"] = plugin.Int(count)
+	info["vendors"] = plugin.List(names)
+	return info
+}
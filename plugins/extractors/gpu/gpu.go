@@ -0,0 +1,135 @@
+package gpu
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/compspec/compspec-go/pkg/plugin"
+	"github.com/compspec/compspec-go/pkg/rootfs"
+	"github.com/compspec/compspec-go/pkg/utils"
+)
+
+const (
+	ExtractorName        = "gpu"
+	ExtractorDescription = "gpu extractor"
+	DevicesSection       = "devices"
+	DriversSection       = "drivers"
+	SummarySection       = "summary"
+)
+
+var (
+	validSections = []string{DevicesSection, DriversSection, SummarySection}
+
+	// Fields for each section, where * is a device index, driver, or vendor
+	validFields = []plugin.FieldSpec{
+		{Section: DevicesSection, Name: "*.address", Description: "pci address of the device", Example: "0000:3b:00.0"},
+		{Section: DevicesSection, Name: "*.vendor", Description: "vendor of the device (nvidia, amd, or intel)", Example: "nvidia"},
+		{Section: DevicesSection, Name: "*.vendor_id", Description: "pci vendor id", Example: "0x10de"},
+		{Section: DevicesSection, Name: "*.device_id", Description: "pci device id", Example: "0x20b0"},
+		{Section: DevicesSection, Name: "*.class", Description: "pci class (0x0300 is vga, 0x0302 is 3d, 0x0380 is display)", Example: "0x030200"},
+		{Section: DevicesSection, Name: "*.integrated", Type: plugin.BoolType, Description: "the device is integrated graphics (intel), which is not counted in the summary", Example: "false"},
+		{Section: DevicesSection, Name: "*.driver", Description: "kernel driver bound to the device", Example: "nvidia"},
+		{Section: DevicesSection, Name: "*.numa_node", Type: plugin.IntType, Description: "numa node of the device (-1 if unknown)", Example: "0"},
+		{Section: DevicesSection, Name: "*.memory", Type: plugin.BytesType, Unit: "bytes", Description: "device memory, where the driver exposes it (amdgpu)", Example: "34342961152"},
+		{Section: DevicesSection, Name: "*.model", Description: "model name, where the driver exposes it (nvidia)", Example: "NVIDIA A100-SXM4-40GB"},
+		{Section: DriversSection, Name: "*.version", Type: plugin.VersionType, Description: "version of a driver for the devices", Example: "535.104.05"},
+		{Section: SummarySection, Name: "available", Type: plugin.BoolType, Description: "there is at least one gpu", Example: "true"},
+		{Section: SummarySection, Name: "count", Type: plugin.IntType, Description: "number of gpus", Example: "4"},
+		{Section: SummarySection, Name: "vendors", Type: plugin.ListType, Description: "vendors of the gpus", Example: "nvidia"},
+		{Section: SummarySection, Name: "*.count", Type: plugin.IntType, Description: "number of gpus for a vendor", Example: "4"},
+	}
+)
+
+// init registers the extractor so it can be found by name
+func init() {
+	plugin.Register(plugin.Registration{
+		Name:        ExtractorName,
+		Description: ExtractorDescription,
+		Kind:        plugin.ExtractorKind,
+		Sections:    validSections,
+		New:         NewPlugin,
+	})
+}
+
+type GPUExtractor struct {
+	sections []string
+}
+
+func (e GPUExtractor) Name() string {
+	return ExtractorName
+}
+
+func (e GPUExtractor) Description() string {
+	return ExtractorDescription
+}
+
+func (e GPUExtractor) Sections() []string {
+	return e.sections
+}
+
+// Validate ensures that the sections provided are in the list we know
+func (e GPUExtractor) Validate() bool {
+	invalids, valid := utils.StringArrayIsSubset(e.sections, validSections)
+	for _, invalid := range invalids {
+		fmt.Fprintf(os.Stderr, "Sections %s is not known for extractor plugin %s\n", invalid, e.Name())
+	}
+	return valid
+}
+
+// Extract returns gpu metadata, for a set of named sections
+func (e GPUExtractor) Extract(ctx context.Context, allowFail bool) (plugin.PluginData, error) {
+	return plugin.ExtractSections(ctx, e, e.sections, allowFail)
+}
+
+// ExtractSection returns gpu metadata for a single named section. Each
+// section finds the devices, which is just reading a few files.
+func (e GPUExtractor) ExtractSection(ctx context.Context, name string) (plugin.PluginSection, error) {
+	root := rootfs.FromContext(ctx)
+	devices, err := getDevices(root)
+	if err != nil {
+		return nil, err
+	}
+	switch name {
+	case DevicesSection:
+		return getDevicesInformation(devices)
+	case DriversSection:
+		return getDriversInformation(root, devices)
+	case SummarySection:
+		return getSummary(devices), nil
+	}
+	return nil, fmt.Errorf("section %s is not known for extractor plugin %s", name, e.Name())
+}
+
+// HostOnly is true for all sections, which describe devices
+func (e GPUExtractor) HostOnly(name string) bool {
+	return true
+}
+
+// Source returns where the data for a section comes from
+func (e GPUExtractor) Source(name string) string {
+	switch name {
+	case DevicesSection, SummarySection:
+		return pciDevices
+	case DriversSection:
+		return nvidiaVersionFile + ", " + moduleDir
+	}
+	return ""
+}
+
+// Fields describes the fields that each section returns
+func (e GPUExtractor) Fields() []plugin.FieldSpec {
+	return validFields
+}
+
+// NewPlugin validates and returns a new gpu plugin
+func NewPlugin(sections []string) (plugin.Plugin, error) {
+	if len(sections) == 0 {
+		sections = validSections
+	}
+	e := GPUExtractor{sections: sections}
+	if !e.Validate() {
+		return nil, fmt.Errorf("plugin %s is not valid", e.Name())
+	}
+	return e, nil
+}
@@ -0,0 +1,369 @@
+package interconnect
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/compspec/compspec-go/pkg/plugin"
+	"github.com/compspec/compspec-go/pkg/rootfs"
+)
+
+// Locations to find rdma devices, network interfaces, and cxi devices
+const (
+	infinibandDir = "/sys/class/infiniband"
+	netDir        = "/sys/class/net"
+	cxiDir        = "/sys/class/cxi"
+)
+
+// Types of fabric, in order of preference for the fabric of a node
+const (
+	Slingshot  = "slingshot"
+	OmniPath   = "omnipath"
+	Infiniband = "infiniband"
+	EFA        = "efa"
+	RoCE       = "roce"
+	Ethernet   = "ethernet"
+)
+
+var (
+	fabrics = []string{Slingshot, OmniPath, Infiniband, EFA, RoCE, Ethernet}
+
+	// Types of network interfaces (ARPHRD_* in linux/if_arp.h)
+	interfaceTypes = map[string]string{
+		"1":  Ethernet,
+		"32": Infiniband,
+	}
+
+	// 100 Gb/sec (4X EDR)
+	regexRate = regexp.MustCompile(`^([\d.]+)\s*Gb/sec(?:\s*\((.+)\))?`)
+)
+
+// A Port is a port of an rdma device
+type Port struct {
+	Number    string
+	State     string
+	PhysState string
+	Rate      float64
+	Speed     string
+	LinkLayer string
+}
+
+// An HCA is an rdma device (a host channel adapter), which is named by
+// the driver and an index (e.g., mlx5_0)
+type HCA struct {
+	Name     string
+	Driver   string
+	Fabric   string
+	HCAType  string
+	BoardID  string
+	NodeType string
+	Firmware string
+	VendorID string
+	DeviceID string
+	NumaNode string
+	Ports    []Port
+}
+
+// An Interface is a network interface of a device (not a virtual one)
+type Interface struct {
+	Name      string
+	Type      string
+	Driver    string
+	OperState string
+	MTU       string
+	Speed     string
+}
+
+// getHCAs finds the rdma devices, sorted by name
+func getHCAs(root rootfs.Root) ([]HCA, error) {
+	entries, err := root.ReadDir(infinibandDir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, plugin.Skipped("%s is not found", infinibandDir)
+	}
+	if err != nil {
+		return nil, err
+	}
+	hcas := []HCA{}
+	for _, entry := range entries {
+		path := filepath.Join(infinibandDir, entry.Name())
+		hca := HCA{
+			Name:     entry.Name(),
+			HCAType:  readAttribute(root, path, "hca_type"),
+			BoardID:  readAttribute(root, path, "board_id"),
+			NodeType: readState(readAttribute(root, path, "node_type")),
+			Firmware: readAttribute(root, path, "fw_ver"),
+			VendorID: readAttribute(root, path, "device/vendor"),
+			DeviceID: readAttribute(root, path, "device/device"),
+			NumaNode: readAttribute(root, path, "device/numa_node"),
+		}
+		driver, err := root.Readlink(filepath.Join(path, "device", "driver"))
+		if err == nil {
+			hca.Driver = filepath.Base(driver)
+		}
+
+		ports, err := root.ReadDir(filepath.Join(path, "ports"))
+		if err == nil {
+			for _, port := range ports {
+				portPath := filepath.Join(path, "ports", port.Name())
+				p := Port{
+					Number:    port.Name(),
+					State:     readState(readAttribute(root, portPath, "state")),
+					PhysState: readState(readAttribute(root, portPath, "phys_state")),
+					LinkLayer: readAttribute(root, portPath, "link_layer"),
+				}
+				match := regexRate.FindStringSubmatch(readAttribute(root, portPath, "rate"))
+				if match != nil {
+					p.Rate, _ = strconv.ParseFloat(match[1], 64)
+					p.Speed = match[2]
+				}
+				hca.Ports = append(hca.Ports, p)
+			}
+		}
+		hca.Fabric = hcaFabric(hca)
+		hcas = append(hcas, hca)
+	}
+	sort.Slice(hcas, func(i, j int) bool { return hcas[i].Name < hcas[j].Name })
+	return hcas, nil
+}
+
+// hcaFabric derives the fabric of an rdma device from the driver (omnipath
+// and efa) or the link layer of the ports (infiniband or roce)
+func hcaFabric(hca HCA) string {
+	switch {
+	case hca.Driver == "hfi1" || strings.HasPrefix(hca.Name, "hfi1"):
+		return OmniPath
+	case hca.Driver == "efa" || strings.HasPrefix(hca.Name, "efa"):
+		return EFA
+	}
+	for _, port := range hca.Ports {
+		switch port.LinkLayer {
+		case "InfiniBand":
+			return Infiniband
+		case "Ethernet":
+			return RoCE
+		}
+	}
+	return ""
+}
+
+// getInterfaces finds the network interfaces of devices, sorted by name.
+// Virtual interfaces (e.g., lo, bridges, veth) don't have a device.
+func getInterfaces(root rootfs.Root) ([]Interface, error) {
+	entries, err := root.ReadDir(netDir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, plugin.Skipped("%s is not found", netDir)
+	}
+	if err != nil {
+		return nil, err
+	}
+	interfaces := []Interface{}
+	for _, entry := range entries {
+		path := filepath.Join(netDir, entry.Name())
+		exists, err := root.Exists(filepath.Join(path, "device"))
+		if err != nil || !exists {
+			continue
+		}
+		kind := readAttribute(root, path, "type")
+		if name, ok := interfaceTypes[kind]; ok {
+			kind = name
+		}
+		iface := Interface{
+			Name:      entry.Name(),
+			Type:      kind,
+			OperState: readAttribute(root, path, "operstate"),
+			MTU:       readAttribute(root, path, "mtu"),
+
+			// The speed (Mb/s) can't be read when the link is down
+			Speed: readAttribute(root, path, "speed"),
+		}
+		driver, err := root.Readlink(filepath.Join(path, "device", "driver"))
+		if err == nil {
+			iface.Driver = filepath.Base(driver)
+		}
+		interfaces = append(interfaces, iface)
+	}
+	sort.Slice(interfaces, func(i, j int) bool { return interfaces[i].Name < interfaces[j].Name })
+	return interfaces, nil
+}
+
+// getCXIDevices finds the cxi devices of Slingshot (e.g., cxi0)
+func getCXIDevices(root rootfs.Root) ([]string, error) {
+	entries, err := root.ReadDir(cxiDir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return []string{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	devices := []string{}
+	for _, entry := range entries {
+		devices = append(devices, entry.Name())
+	}
+	sort.Strings(devices)
+	return devices, nil
+}
+
+// readAttribute reads a (one line) attribute of a device, which is empty
+// if the device does not have it
+func readAttribute(root rootfs.Root, path, name string) string {
+	raw, err := root.ReadFile(filepath.Join(path, name))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(raw))
+}
+
+// readState removes the number from a state (4: ACTIVE)
+func readState(state string) string {
+	_, name, ok := strings.Cut(state, ":")
+	if !ok {
+		return state
+	}
+	return strings.TrimSpace(name)
+}
+
+// getInfinibandInformation returns fields for each rdma device and port
+func getInfinibandInformation(hcas []HCA) (plugin.PluginSection, error) {
+	if len(hcas) == 0 {
+		return nil, plugin.Skipped("no rdma devices were found")
+	}
+	info := plugin.PluginSection{}
+	for _, hca := range hcas {
+		prefix := hca.Name + "."
+		attributes := map[string]string{
+			"hca_type":  hca.HCAType,
+			"board_id":  hca.BoardID,
+			"node_type": hca.NodeType,
+			"driver":    hca.Driver,
+			"fabric":    hca.Fabric,
+			"vendor_id": hca.VendorID,
+			"device_id": hca.DeviceID,
+		}
+		for name, value := range attributes {
+			if value != "" {
+				info[prefix+name] = plugin.String(value)
+			}
+		}
+		if hca.Firmware != "" {
+			info[prefix+"firmware"] = plugin.Version(hca.Firmware)
+		}
+		if numaNode, err := strconv.ParseInt(hca.NumaNode, 10, 64); err == nil {
+			info[prefix+"numa_node"] = plugin.Int(numaNode)
+		}
+		for _, port := range hca.Ports {
+			portPrefix := fmt.Sprintf("%sport.%s.", prefix, port.Number)
+			info[portPrefix+"state"] = plugin.String(port.State)
+			info[portPrefix+"phys_state"] = plugin.String(port.PhysState)
+			if port.LinkLayer != "" {
+				info[portPrefix+"link_layer"] = plugin.String(port.LinkLayer)
+			}
+			if port.Rate > 0 {
+				info[portPrefix+"rate"] = plugin.Float(port.Rate)
+			}
+			if port.Speed != "" {
+				info[portPrefix+"speed"] = plugin.String(port.Speed)
+			}
+		}
+	}
+	return info, nil
+}
+
+// getNetInformation returns fields for each network interface
+func getNetInformation(interfaces []Interface) (plugin.PluginSection, error) {
+	if len(interfaces) == 0 {
+		return nil, plugin.Skipped("no network interfaces of devices were found")
+	}
+	info := plugin.PluginSection{}
+	for _, iface := range interfaces {
+		prefix := iface.Name + "."
+		info[prefix+"type"] = plugin.String(iface.Type)
+		info[prefix+"operstate"] = plugin.String(iface.OperState)
+		if iface.Driver != "" {
+			info[prefix+"driver"] = plugin.String(iface.Driver)
+		}
+		if mtu, err := strconv.ParseInt(iface.MTU, 10, 64); err == nil {
+			info[prefix+"mtu"] = plugin.Int(mtu)
+		}
+
+		// An unknown speed is -1
+		if speed, err := strconv.ParseInt(iface.Speed, 10, 64); err == nil && speed > 0 {
+			info[prefix+"speed"] = plugin.Int(speed)
+		}
+	}
+	return info, nil
+}
+
+// getFabric finds all of the devices to summarize the fabrics. A node
+// without rdma devices (or network interfaces) just has fewer fabrics.
+func getFabric(root rootfs.Root) (plugin.PluginSection, error) {
+	hcas, err := getHCAs(root)
+	if plugin.IsFailure(err) {
+		return nil, err
+	}
+	interfaces, err := getInterfaces(root)
+	if plugin.IsFailure(err) {
+		return nil, err
+	}
+	cxiDevices, err := getCXIDevices(root)
+	if err != nil {
+		return nil, err
+	}
+	return getFabricInformation(hcas, interfaces, cxiDevices), nil
+}
+
+// getFabricInformation summarizes the fabrics of the node. The type is the
+// fabric we prefer (in the order of fabrics) and the rate is the fastest
+// active link (Gb/s) of an rdma port or a network interface that is up.
+func getFabricInformation(hcas []HCA, interfaces []Interface, cxiDevices []string) plugin.PluginSection {
+	counts := map[string]int64{}
+	rate := 0.0
+	if len(cxiDevices) > 0 {
+		counts[Slingshot] = int64(len(cxiDevices))
+	}
+	for _, hca := range hcas {
+		if hca.Fabric != "" {
+			counts[hca.Fabric] += 1
+		}
+		for _, port := range hca.Ports {
+			if port.State == "ACTIVE" && port.Rate > rate {
+				rate = port.Rate
+			}
+		}
+	}
+	for _, iface := range interfaces {
+		// Slingshot interfaces (hsn0) are counted by their cxi device
+		if iface.Type == Ethernet && !strings.HasPrefix(iface.Driver, "cxi") {
+			counts[Ethernet] += 1
+		}
+		speed, err := strconv.ParseFloat(iface.Speed, 64)
+		if err == nil && iface.OperState == "up" && speed/1000 > rate {
+			rate = speed / 1000
+		}
+	}
+
+	info := plugin.PluginSection{}
+	types := []string{}
+	for _, fabric := range fabrics {
+		count, ok := counts[fabric]
+		if !ok {
+			continue
+		}
+		types = append(types, fabric)
+		info[fabric+".count"] = plugin.Int(count)
+	}
+	if len(types) > 0 {
+		info["type"] = plugin.String(types[0])
+	}
+	if rate > 0 {
+		info["rate"] = plugin.Float(rate)
+	}
+	info["types"] = plugin.List(types)
+	info["available"] = plugin.Bool(len(types) > 0 && types[0] != Ethernet)
+	return info
+}
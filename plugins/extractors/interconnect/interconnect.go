@@ -0,0 +1,149 @@
+package interconnect
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/compspec/compspec-go/pkg/plugin"
+	"github.com/compspec/compspec-go/pkg/rootfs"
+	"github.com/compspec/compspec-go/pkg/utils"
+)
+
+const (
+	ExtractorName        = "interconnect"
+	ExtractorDescription = "high speed interconnect extractor"
+	InfinibandSection    = "infiniband"
+	NetSection           = "net"
+	FabricSection        = "fabric"
+)
+
+var (
+	validSections = []string{InfinibandSection, NetSection, FabricSection}
+
+	// Fields for each section, where * is a device, port, interface, or fabric
+	validFields = []plugin.FieldSpec{
+		{Section: InfinibandSection, Name: "*.hca_type", Description: "model of the host channel adapter", Example: "MT4123"},
+		{Section: InfinibandSection, Name: "*.board_id", Description: "board id of the adapter", Example: "MT_0000000223"},
+		{Section: InfinibandSection, Name: "*.node_type", Description: "type of the node (CA is a channel adapter)", Example: "CA"},
+		{Section: InfinibandSection, Name: "*.firmware", Type: plugin.VersionType, Description: "firmware version of the adapter", Example: "20.31.1014"},
+		{Section: InfinibandSection, Name: "*.driver", Description: "kernel driver of the adapter", Example: "mlx5_core"},
+		{Section: InfinibandSection, Name: "*.fabric", Description: "fabric of the adapter (infiniband, roce, omnipath, or efa)", Example: "infiniband"},
+		{Section: InfinibandSection, Name: "*.vendor_id", Description: "pci vendor id", Example: "0x15b3"},
+		{Section: InfinibandSection, Name: "*.device_id", Description: "pci device id", Example: "0x101b"},
+		{Section: InfinibandSection, Name: "*.numa_node", Type: plugin.IntType, Description: "numa node of the adapter (-1 if unknown)", Example: "0"},
+		{Section: InfinibandSection, Name: "*.port.*.state", Description: "logical state of a port", Example: "ACTIVE"},
+		{Section: InfinibandSection, Name: "*.port.*.phys_state", Description: "physical state of a port", Example: "LinkUp"},
+		{Section: InfinibandSection, Name: "*.port.*.link_layer", Description: "link layer of a port (InfiniBand or Ethernet)", Example: "InfiniBand"},
+		{Section: InfinibandSection, Name: "*.port.*.rate", Type: plugin.FloatType, Unit: "Gb/s", Description: "link rate of a port", Example: "200"},
+		{Section: InfinibandSection, Name: "*.port.*.speed", Description: "link width and speed of a port", Example: "4X HDR"},
+		{Section: NetSection, Name: "*.type", Description: "type of the interface (ethernet, infiniband, or the arp hardware type)", Example: "ethernet"},
+		{Section: NetSection, Name: "*.operstate", Description: "operational state of the interface", Example: "up"},
+		{Section: NetSection, Name: "*.driver", Description: "kernel driver of the device", Example: "ice"},
+		{Section: NetSection, Name: "*.mtu", Type: plugin.IntType, Unit: "bytes", Description: "maximum transmission unit", Example: "9000"},
+		{Section: NetSection, Name: "*.speed", Type: plugin.IntType, Unit: "Mb/s", Description: "link speed, when the link is up", Example: "100000"},
+		{Section: FabricSection, Name: "type", Description: "fabric of the node (slingshot, omnipath, infiniband, efa, roce, or ethernet)", Example: "infiniband"},
+		{Section: FabricSection, Name: "types", Type: plugin.ListType, Description: "all fabrics of the node", Example: "infiniband ethernet"},
+		{Section: FabricSection, Name: "available", Type: plugin.BoolType, Description: "there is a high speed fabric (not just ethernet)", Example: "true"},
+		{Section: FabricSection, Name: "rate", Type: plugin.FloatType, Unit: "Gb/s", Description: "fastest active link", Example: "200"},
+		{Section: FabricSection, Name: "*.count", Type: plugin.IntType, Description: "number of devices for a fabric", Example: "4"},
+	}
+)
+
+// init registers the extractor so it can be found by name
+func init() {
+	plugin.Register(plugin.Registration{
+		Name:        ExtractorName,
+		Description: ExtractorDescription,
+		Kind:        plugin.ExtractorKind,
+		Sections:    validSections,
+		New:         NewPlugin,
+	})
+}
+
+type InterconnectExtractor struct {
+	sections []string
+}
+
+func (e InterconnectExtractor) Name() string {
+	return ExtractorName
+}
+
+func (e InterconnectExtractor) Description() string {
+	return ExtractorDescription
+}
+
+func (e InterconnectExtractor) Sections() []string {
+	return e.sections
+}
+
+// Validate ensures that the sections provided are in the list we know
+func (e InterconnectExtractor) Validate() bool {
+	invalids, valid := utils.StringArrayIsSubset(e.sections, validSections)
+	for _, invalid := range invalids {
+		fmt.Fprintf(os.Stderr, "Sections %s is not known for extractor plugin %s\n", invalid, e.Name())
+	}
+	return valid
+}
+
+// Extract returns interconnect metadata, for a set of named sections
+func (e InterconnectExtractor) Extract(ctx context.Context, allowFail bool) (plugin.PluginData, error) {
+	return plugin.ExtractSections(ctx, e, e.sections, allowFail)
+}
+
+// ExtractSection returns interconnect metadata for a single named section
+func (e InterconnectExtractor) ExtractSection(ctx context.Context, name string) (plugin.PluginSection, error) {
+	root := rootfs.FromContext(ctx)
+	switch name {
+	case InfinibandSection:
+		hcas, err := getHCAs(root)
+		if err != nil {
+			return nil, err
+		}
+		return getInfinibandInformation(hcas)
+	case NetSection:
+		interfaces, err := getInterfaces(root)
+		if err != nil {
+			return nil, err
+		}
+		return getNetInformation(interfaces)
+	case FabricSection:
+		return getFabric(root)
+	}
+	return nil, fmt.Errorf("section %s is not known for extractor plugin %s", name, e.Name())
+}
+
+// HostOnly is true for all sections, which describe devices
+func (e InterconnectExtractor) HostOnly(name string) bool {
+	return true
+}
+
+// Source returns where the data for a section comes from
+func (e InterconnectExtractor) Source(name string) string {
+	switch name {
+	case InfinibandSection:
+		return infinibandDir
+	case NetSection:
+		return netDir
+	case FabricSection:
+		return infinibandDir + ", " + netDir + ", " + cxiDir
+	}
+	return ""
+}
+
+// Fields describes the fields that each section returns
+func (e InterconnectExtractor) Fields() []plugin.FieldSpec {
+	return validFields
+}
+
+// NewPlugin validates and returns a new interconnect plugin
+func NewPlugin(sections []string) (plugin.Plugin, error) {
+	if len(sections) == 0 {
+		sections = validSections
+	}
+	e := InterconnectExtractor{sections: sections}
+	if !e.Validate() {
+		return nil, fmt.Errorf("plugin %s is not valid", e.Name())
+	}
+	return e, nil
+}
@@ -1,11 +1,12 @@
 package kernel
 
 import (
+	"context"
 	"fmt"
-	"os"
 	"strings"
 
 	"github.com/compspec/compspec-go/pkg/plugin"
+	"github.com/compspec/compspec-go/pkg/rootfs"
 	"github.com/compspec/compspec-go/pkg/utils"
 	kernelParser "github.com/moby/moby/pkg/parsers/kernel"
 )
@@ -21,43 +22,77 @@ const (
 
 	// Directory with metadata about kernel modules (drivers/versions/params)!
 	kernelModules = "/sys/module"
+
+	// The kernel release, for a root that is not the host
+	kernelReleaseFile = "/proc/sys/kernel/osrelease"
 )
 
+// getKernelVersion returns the running kernel version. For another root
+// we can't ask the kernel (it's the host) so we read what the root has.
+func getKernelVersion(root rootfs.Root) (string, error) {
+	if root.IsHost() {
+		version, err := kernelParser.GetKernelVersion()
+		if err != nil {
+			return "", err
+		}
+		return version.String(), nil
+	}
+	raw, err := root.ReadFile(kernelReleaseFile)
+	if err != nil {
+		return "", fmt.Errorf("cannot determine kernel version for root %s: %s", root, err)
+	}
+	return strings.TrimSpace(string(raw)), nil
+}
+
 // getKernelBootParams loads parameters given to the kernel at boot time
-func getKernelBootParams() (plugin.PluginSection, error) {
+func getKernelBootParams(root rootfs.Root) (plugin.PluginSection, error) {
 
-	raw, err := os.ReadFile(kernelBootFile)
+	raw, err := root.ReadFile(kernelBootFile)
 	if err != nil {
 		return nil, err
 	}
 
 	args := strings.Split(strings.TrimSpace(string(raw)), " ")
-	return utils.SplitDelimiterList(args, "=")
+	params, err := utils.SplitDelimiterList(args, "=")
+	if err != nil {
+		return nil, err
+	}
+	return plugin.NewSection(params), nil
 }
 
-// getKernelBootConfig loads key value pairs from the kernel config
-func getKernelBootConfig() (plugin.PluginSection, error) {
+// getKernelBootConfig loads key value pairs from the kernel config, which
+// is for the kernel version in /boot unless we are given a path
+func getKernelBootConfig(root rootfs.Root, path string) (plugin.PluginSection, error) {
 
-	version, err := kernelParser.GetKernelVersion()
+	// What about other files in this directory (older or not active versions?)
+	if path == "" {
+		version, err := getKernelVersion(root)
+		if err != nil {
+			return nil, err
+		}
+		path = kernelConfigPrefix + version
+	}
+	configPath, err := root.Path(path)
 	if err != nil {
 		return nil, err
 	}
-
-	// What about other files in this directory (older or not active versions?)
-	configPath := fmt.Sprintf("/boot/config-%s", version)
-	return utils.ParseConfigFile(configPath, "#", "=")
+	config, err := utils.ParseConfigFile(configPath, "#", "=")
+	if err != nil {
+		return nil, err
+	}
+	return plugin.NewSection(config), nil
 }
 
 // getKernelModules flattens the list of kernel modules (drivers) into
 // the name (and if enabled) and version. I don't know if we need more than that.
-func getKernelModules() (plugin.PluginSection, error) {
-	version, err := kernelParser.GetKernelVersion()
+func getKernelModules(ctx context.Context, root rootfs.Root) (plugin.PluginSection, error) {
+	version, err := getKernelVersion(root)
 	if err != nil {
 		return nil, err
 	}
 
 	// The directories in this folder are the modules!
-	moduleDirs, err := os.ReadDir(kernelModules)
+	moduleDirs, err := root.ReadDir(kernelModules)
 	if err != nil {
 		return nil, err
 	}
@@ -69,6 +104,11 @@ func getKernelModules() (plugin.PluginSection, error) {
 	modules := plugin.PluginSection{}
 	for _, moduleDir := range moduleDirs {
 
+		// There can be many modules, stop if we are cancelled or timed out
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
 		// Don't look unless it's a directory
 		if !moduleDir.IsDir() {
 			continue
@@ -77,7 +117,7 @@ func getKernelModules() (plugin.PluginSection, error) {
 		// Get the name, and then we can create a module
 		// This parses the version and name
 		moduleName := moduleDir.Name()
-		module := NewModule(moduleName, version.String())
+		module := NewModule(root, moduleName, version)
 
 		// This can error, and we'd want to know about it
 		err := module.SetParameters()
@@ -85,10 +125,10 @@ func getKernelModules() (plugin.PluginSection, error) {
 			return nil, err
 		}
 		// Add module paramters to our data
-		modules[module.Key()] = module.Version
+		modules[module.Key()] = plugin.String(module.Version)
 		for param, value := range module.Parameters {
 			moduleParam := fmt.Sprintf("%s.parameter.%s", module.Key(), param)
-			modules[moduleParam] = value
+			modules[moduleParam] = plugin.String(value)
 		}
 	}
 	return modules, nil
@@ -1,9 +1,12 @@
 package kernel
 
 import (
+	"context"
 	"fmt"
+	"os"
 
 	"github.com/compspec/compspec-go/pkg/plugin"
+	"github.com/compspec/compspec-go/pkg/rootfs"
 	"github.com/compspec/compspec-go/pkg/utils"
 )
 
@@ -13,12 +16,36 @@ const (
 	KernelBootSection    = "boot"
 	KernelConfigSection  = "config"
 	KernelModulesSection = "modules"
+
+	// The kernel config file, when it is not in /boot
+	ConfigPathOption = "config.path"
 )
 
 var (
 	validSections = []string{KernelBootSection, KernelConfigSection, KernelModulesSection}
+	validFields   = []plugin.FieldSpec{
+		{Section: KernelBootSection, Name: "*", Description: "a parameter on the kernel command line (empty without a value)", Example: "/dev/sda1"},
+		{Section: KernelConfigSection, Name: "*", Description: "a kernel config option (y, m, or a value)", Example: "y"},
+		{Section: KernelModulesSection, Name: "module.*", Description: "version of a kernel module (the kernel version for a builtin)", Example: "6.1.0-13-amd64"},
+		{Section: KernelModulesSection, Name: "module.*.parameter.*", Description: "a parameter of a kernel module", Example: "0"},
+	}
+	validOptions = []plugin.Option{
+		{Name: ConfigPathOption, Type: plugin.StringType, Description: "kernel config file (defaults to /boot/config-<version>)"},
+	}
 )
 
+// init registers the extractor so it can be found by name
+func init() {
+	plugin.Register(plugin.Registration{
+		Name:        ExtractorName,
+		Description: ExtractorDescription,
+		Kind:        plugin.ExtractorKind,
+		Sections:    validSections,
+		Options:     validOptions,
+		New:         NewPlugin,
+	})
+}
+
 type KernelExtractor struct {
 	sections []string
 }
@@ -27,10 +54,6 @@ func (e KernelExtractor) Description() string {
 	return ExtractorDescription
 }
 
-func (e KernelExtractor) Create(plugin.PluginOptions) error { return nil }
-func (e KernelExtractor) IsCreator() bool                   { return false }
-func (e KernelExtractor) IsExtractor() bool                 { return true }
-
 func (e KernelExtractor) Sections() []string {
 	return e.sections
 }
@@ -45,54 +68,61 @@ func (c KernelExtractor) Name() string {
 func (c KernelExtractor) Validate() bool {
 	invalids, valid := utils.StringArrayIsSubset(c.sections, validSections)
 	for _, invalid := range invalids {
-		fmt.Printf("Sections %s is not known for extractor plugin %s\n", invalid, c.Name())
+		fmt.Fprintf(os.Stderr, "Sections %s is not known for extractor plugin %s\n", invalid, c.Name())
 	}
 	return valid
 }
 
 // Extract returns kernel metadata, for a set of named sections
-// TODO eventually the user could select which sections they want
-func (c KernelExtractor) Extract(allowFail bool) (plugin.PluginData, error) {
-
-	sections := map[string]plugin.PluginSection{}
-	data := plugin.PluginData{}
-
-	// Only extract the sections we asked for
-	for _, name := range c.sections {
-
-		// Boot!
-		if name == KernelBootSection {
-			section, err := getKernelBootParams()
-			if err != nil && !allowFail {
-				return data, err
-			}
-			sections[KernelBootSection] = section
-		}
-
-		// Kernel full config file
-		if name == KernelConfigSection {
-			section, err := getKernelBootConfig()
-			if err != nil && !allowFail {
-				return data, err
-			}
-			sections[KernelConfigSection] = section
-		}
-
-		// Kernel full config file
-		if name == KernelModulesSection {
-			section, err := getKernelModules()
-			if err != nil && !allowFail {
-				return data, err
-			}
-			sections[KernelModulesSection] = section
-		}
+func (c KernelExtractor) Extract(ctx context.Context, allowFail bool) (plugin.PluginData, error) {
+	return plugin.ExtractSections(ctx, c, c.sections, allowFail)
+}
+
+// ExtractSection returns kernel metadata for a single named section
+func (c KernelExtractor) ExtractSection(ctx context.Context, name string) (plugin.PluginSection, error) {
+	root := rootfs.FromContext(ctx)
+	switch name {
+
+	// Boot!
+	case KernelBootSection:
+		return getKernelBootParams(root)
+
+	// Kernel full config file
+	case KernelConfigSection:
+		return getKernelBootConfig(root, plugin.OptionsFromContext(ctx).String(ConfigPathOption))
+
+	// Kernel modules (drivers)
+	case KernelModulesSection:
+		return getKernelModules(ctx, root)
 	}
-	data.Sections = sections
-	return data, nil
+	return nil, fmt.Errorf("section %s is not known for extractor plugin %s", name, c.Name())
+}
+
+// HostOnly is true for all sections, which describe the running kernel
+func (c KernelExtractor) HostOnly(name string) bool {
+	return true
+}
+
+// Source returns where the data for a section comes from
+func (c KernelExtractor) Source(name string) string {
+	switch name {
+	case KernelBootSection:
+		return kernelBootFile
+	case KernelConfigSection:
+		return kernelConfigPrefix + "<version>"
+	case KernelModulesSection:
+		return kernelModules
+	}
+	return ""
+}
+
+// Fields describes the fields that each section returns
+func (c KernelExtractor) Fields() []plugin.FieldSpec {
+	return validFields
 }
 
 // NewPlugin validates and returns a new kernel plugins
-func NewPlugin(sections []string) (plugin.PluginInterface, error) {
+func NewPlugin(sections []string) (plugin.Plugin, error) {
 	if len(sections) == 0 {
 		sections = validSections
 	}
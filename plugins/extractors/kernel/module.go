@@ -8,6 +8,7 @@ import (
 	"path/filepath"
 	"strings"
 
+	"github.com/compspec/compspec-go/pkg/rootfs"
 	"golang.org/x/sys/unix"
 )
 
@@ -17,21 +18,24 @@ type Module struct {
 	Path       string
 	Version    string
 	Parameters map[string]string
+
+	// The filesystem root that the path is in
+	root rootfs.Root
 }
 
 // NewModule prepares a module for a path
-func NewModule(name, defaultVersion string) *Module {
+func NewModule(root rootfs.Root, name, defaultVersion string) *Module {
 
 	// Get the name, and then we can read the fullpath
 	fullPath := filepath.Join(kernelModules, name)
-	module := Module{Name: name, Path: fullPath}
+	module := Module{Name: name, Path: fullPath, root: root}
 	module.setVersion(defaultVersion)
 	return &module
 }
 
 // Derive the version of the module
 func (m *Module) setVersion(version string) {
-	v, err := os.ReadFile(filepath.Join(m.Path, "version"))
+	v, err := m.root.ReadFile(filepath.Join(m.Path, "version"))
 
 	// If we don't have an error, use the derived version
 	if err == nil {
@@ -54,7 +58,7 @@ func (m *Module) parameterPath() string {
 func (m *Module) SetParameters() error {
 
 	// parameters are in the module directory here
-	params, err := os.ReadDir(m.parameterPath())
+	params, err := m.root.ReadDir(m.parameterPath())
 	if err != nil {
 
 		// OK if no parameters
@@ -82,13 +86,13 @@ func (m *Module) SetParameters() error {
 // readParameterFile reads a parameter file and returns the value
 func (m *Module) readParameterFile(name string) (string, error) {
 
-	content, err := os.ReadFile(filepath.Join(m.parameterPath(), name))
+	content, err := m.root.ReadFile(filepath.Join(m.parameterPath(), name))
 	if err != nil {
 		var pathErr *fs.PathError
 
 		// TODO how often does this happen? Skip for now
 		if errors.As(err, &pathErr) && pathErr.Err == unix.EPERM || pathErr.Err == unix.EACCES {
-			fmt.Printf("Warning: cannot read parameter path because of EPERM and EACCES: %s\n", err)
+			fmt.Fprintf(os.Stderr, "Warning: cannot read parameter path because of EPERM and EACCES: %s\n", err)
 		}
 	}
 	return strings.TrimSpace(string(content)), nil
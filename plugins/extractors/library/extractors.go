@@ -1,12 +1,16 @@
 package library
 
 import (
+	"context"
 	"fmt"
+	"os"
 	"os/exec"
+	"path/filepath"
 	"regexp"
 	"strings"
 
 	"github.com/compspec/compspec-go/pkg/plugin"
+	"github.com/compspec/compspec-go/pkg/rootfs"
 	"github.com/compspec/compspec-go/pkg/utils"
 )
 
@@ -15,23 +19,52 @@ const (
 )
 
 var (
-	regexIntelMPIVersion = regexp.MustCompile(`Version (.*) `)
+	regexIntelMPIVersion  = regexp.MustCompile(`Version (.*) `)
+	regexPkgConfigVersion = regexp.MustCompile(`(?m)^Version:\s*(\S+)`)
+
+	// pkg-config files for MPI variants, for when we can't run mpirun
+	mpiPkgConfigFiles = [][2]string{
+		{"ompi.pc", "OpenMPI"},
+		{"mpich.pc", "mpich"},
+		{"impi.pc", "intel-mpi"},
+	}
+
+	// Where we look for pkg-config files, in order
+	pkgConfigDirs = []string{
+		"/usr/lib/pkgconfig",
+		"/usr/lib64/pkgconfig",
+		"/usr/lib/*/pkgconfig",
+		"/usr/local/lib/pkgconfig",
+		"/usr/share/pkgconfig",
+		"/usr/lib/*/*/lib/pkgconfig",
+		"/usr/lib64/*/lib/pkgconfig",
+		"/opt/*/lib/pkgconfig",
+		"/opt/intel/oneapi/mpi/*/lib/pkgconfig",
+	}
 )
 
 // getMPIInformation returns info on mpi versions and variant
 // yes, fairly janky, please improve upon! This is for a prototype
-func getMPIInformation() (plugin.PluginSection, error) {
+func getMPIInformation(ctx context.Context, root rootfs.Root, mpirun string) (plugin.PluginSection, error) {
 	info := plugin.PluginSection{}
 
+	// We would find (and run) mpirun on the host, not in the root
+	if !root.IsHost() {
+		return getMPIPkgConfig(root)
+	}
+
 	// Do we even have mpirun?
-	path, err := exec.LookPath(MPIRunExec)
+	if mpirun == "" {
+		mpirun = MPIRunExec
+	}
+	path, err := exec.LookPath(mpirun)
 	if err != nil {
-		return info, nil
+		return info, plugin.Skipped("%s is not installed", mpirun)
 	}
 
 	// Get output from the tool
-	command := []string{MPIRunExec, "--version"}
-	output, err := utils.RunCommand(command)
+	command := []string{path, "--version"}
+	output, err := utils.RunCommandContext(ctx, command)
 	if err != nil {
 		return info, err
 	}
@@ -41,19 +74,19 @@ func getMPIInformation() (plugin.PluginSection, error) {
 	for _, line := range lines {
 		line = strings.TrimSpace(line)
 		if strings.Contains(line, "Open MPI") {
-			info["variant"] = "OpenMPI"
+			info["variant"] = plugin.String("OpenMPI")
 			parts := strings.Split(line, " ")
-			info["version"] = parts[len(parts)-1]
+			info["version"] = plugin.Version(parts[len(parts)-1])
 			return info, nil
 		}
 
 		// Intel(R) MPI Library for Linux* OS, Version 2021.8 Build 20221129 (id: 339ec755a1)
 		if strings.Contains(line, "Intel") {
-			info["variant"] = "intel-mpi"
+			info["variant"] = plugin.String("intel-mpi")
 			match := regexIntelMPIVersion.FindStringSubmatch(line)
 			if match != nil {
 				parts := strings.Split(match[0], " ")
-				info["version"] = parts[1]
+				info["version"] = plugin.Version(parts[1])
 			}
 			return info, nil
 		}
@@ -61,15 +94,42 @@ func getMPIInformation() (plugin.PluginSection, error) {
 		// Note that for mpich there is a LOT more metadata
 		// Right now I'm assuming if we find Version: it's for Open MPI
 		if strings.Contains(line, "Version:") {
-			info["variant"] = "mpich"
+			info["variant"] = plugin.String("mpich")
 			parts := strings.Split(line, " ")
-			info["version"] = parts[len(parts)-1]
+			info["version"] = plugin.Version(parts[len(parts)-1])
 			return info, nil
 		}
 
 	}
 
-	fmt.Println(output)
-	fmt.Printf("%s is available at %s\n", MPIRunExec, path)
+	fmt.Fprintln(os.Stderr, output)
+	fmt.Fprintf(os.Stderr, "%s is available at %s\n", mpirun, path)
 	return info, nil
 }
+
+// getMPIPkgConfig finds the mpi variant and version from pkg-config files,
+// which we can read for a root (e.g., an image) where we can't run mpirun
+func getMPIPkgConfig(root rootfs.Root) (plugin.PluginSection, error) {
+	info := plugin.PluginSection{}
+	for _, dir := range pkgConfigDirs {
+		dirs, err := root.Glob(dir)
+		if err != nil {
+			return nil, err
+		}
+		for _, dir := range dirs {
+			for _, pkgConfig := range mpiPkgConfigFiles {
+				raw, err := root.ReadFile(filepath.Join(dir, pkgConfig[0]))
+				if err != nil {
+					continue
+				}
+				info["variant"] = plugin.String(pkgConfig[1])
+				match := regexPkgConfigVersion.FindStringSubmatch(string(raw))
+				if match != nil {
+					info["version"] = plugin.Version(match[1])
+				}
+				return info, nil
+			}
+		}
+	}
+	return nil, plugin.Skipped("MPI pkg-config files are not found in root %s", root)
+}
@@ -0,0 +1,228 @@
+package library
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/compspec/compspec-go/pkg/plugin"
+	"github.com/compspec/compspec-go/pkg/rootfs"
+	"github.com/compspec/compspec-go/pkg/utils"
+)
+
+const (
+	FiInfoExec  = "fi_info"
+	UCXInfoExec = "ucx_info"
+)
+
+var (
+	// fi_info: 1.18.0
+	// libfabric: 1.18.0
+	// libfabric api: 1.18
+	regexLibfabricVersion    = regexp.MustCompile(`(?m)^libfabric:\s*(\S+)`)
+	regexLibfabricAPIVersion = regexp.MustCompile(`(?m)^libfabric api:\s*(\S+)`)
+
+	// fi_info -l lists each provider (e.g., verbs:) and its version
+	//
+	// verbs:
+	//     version: 118.0
+	regexLibfabricProvider = regexp.MustCompile(`(?m)^([\w;]+):\s*\n(?:\s+version:\s*(\S+))?`)
+
+	// The version of a library is after the .so (libfabric.so.1.21.0)
+	regexSonameVersion = regexp.MustCompile(`\.so\.(\d+(?:\.\d+)*)$`)
+
+	// # Library version: 1.15.0 (or # UCT version=1.9.0 for older versions)
+	regexUCXVersion   = regexp.MustCompile(`(?:Library version:|UCT version=)\s*(\d+(?:\.\d+)+)`)
+	regexUCXTransport = regexp.MustCompile(`(?m)^#\s*Transport:\s*(\S+)`)
+	regexUCXDevice    = regexp.MustCompile(`(?m)^#\s*Device:\s*(\S+)`)
+
+	// Where we look for libfabric and ucx libraries, in order
+	libraryDirs = []string{
+		"/usr/lib64",
+		"/usr/lib",
+		"/usr/lib/*-linux-gnu",
+		"/usr/local/lib64",
+		"/usr/local/lib",
+		"/opt/*/lib64",
+		"/opt/*/lib",
+		"/opt/cray/libfabric/*/lib64",
+	}
+)
+
+// A fabric library, the tool that describes it (and how to run it),
+// and the library and pkg-config file that we can find instead
+type fabricLibrary struct {
+	name      string
+	tool      string
+	extract   func(context.Context, string, plugin.PluginSection) error
+	library   string
+	pkgConfig string
+}
+
+var (
+	fabricLibraries = []fabricLibrary{
+		{name: "libfabric", tool: FiInfoExec, extract: getLibfabricInformation, library: "libfabric.so*", pkgConfig: "libfabric.pc"},
+		{name: "ucx", tool: UCXInfoExec, extract: getUCXInformation, library: "libucp.so*", pkgConfig: "ucx.pc"},
+	}
+)
+
+// getFabricInformation returns the versions of libfabric and ucx, and the
+// providers (libfabric) and transports (ucx) they have. On the host we ask
+// fi_info and ucx_info, and otherwise we can only find the libraries. When
+// a tool fails, we find its library instead, and the section is partial.
+func getFabricInformation(ctx context.Context, root rootfs.Root) (plugin.PluginSection, error) {
+	info := plugin.PluginSection{}
+	failures := []string{}
+	for _, library := range fabricLibraries {
+		found := false
+		if root.IsHost() {
+			var err error
+			found, err = library.run(ctx, info)
+			if err != nil {
+				failures = append(failures, fmt.Sprintf("%s: %s", library.tool, err))
+				found = false
+			}
+		}
+		if !found {
+			err := library.find(root, info)
+			if err != nil {
+				failures = append(failures, fmt.Sprintf("%s: %s", library.name, err))
+			}
+		}
+	}
+	if len(failures) > 0 {
+		err := fmt.Errorf("%s", strings.Join(failures, "; "))
+		if len(info) == 0 {
+			return nil, err
+		}
+		return info, plugin.Partial(err)
+	}
+	if len(info) == 0 {
+		return nil, plugin.Skipped("libfabric and ucx are not found in root %s", root)
+	}
+	return info, nil
+}
+
+// run adds the fields for a library from its tool, if it is installed
+func (l fabricLibrary) run(ctx context.Context, info plugin.PluginSection) (bool, error) {
+	path, err := exec.LookPath(l.tool)
+	if err != nil {
+		return false, nil
+	}
+	return true, l.extract(ctx, path, info)
+}
+
+// getLibfabricInformation runs fi_info for the version and providers
+func getLibfabricInformation(ctx context.Context, path string, info plugin.PluginSection) error {
+	output, err := utils.RunCommandContext(ctx, []string{path, "--version"})
+	if err != nil {
+		return err
+	}
+	match := regexLibfabricVersion.FindStringSubmatch(output)
+	if match != nil {
+		info["libfabric.version"] = plugin.Version(match[1])
+	}
+	match = regexLibfabricAPIVersion.FindStringSubmatch(output)
+	if match != nil {
+		info["libfabric.api_version"] = plugin.Version(match[1])
+	}
+
+	output, err = utils.RunCommandContext(ctx, []string{path, "-l"})
+	if err != nil {
+		return err
+	}
+	providers := []string{}
+	for _, match := range regexLibfabricProvider.FindAllStringSubmatch(output, -1) {
+		providers = append(providers, match[1])
+		if match[2] != "" {
+			info["libfabric.provider."+match[1]+".version"] = plugin.Version(match[2])
+		}
+	}
+	info["libfabric.providers"] = plugin.List(unique(providers))
+	return nil
+}
+
+// getUCXInformation runs ucx_info for the version, transports, and devices
+func getUCXInformation(ctx context.Context, path string, info plugin.PluginSection) error {
+	output, err := utils.RunCommandContext(ctx, []string{path, "-v"})
+	if err != nil {
+		return err
+	}
+	match := regexUCXVersion.FindStringSubmatch(output)
+	if match != nil {
+		info["ucx.version"] = plugin.Version(match[1])
+	}
+
+	output, err = utils.RunCommandContext(ctx, []string{path, "-d"})
+	if err != nil {
+		return err
+	}
+	transports := []string{}
+	for _, match := range regexUCXTransport.FindAllStringSubmatch(output, -1) {
+		transports = append(transports, match[1])
+	}
+	devices := []string{}
+	for _, match := range regexUCXDevice.FindAllStringSubmatch(output, -1) {
+		devices = append(devices, match[1])
+	}
+	info["ucx.transports"] = plugin.List(unique(transports))
+	info["ucx.devices"] = plugin.List(unique(devices))
+	return nil
+}
+
+// find adds the path of a library and the version of the library from
+// its name (libfabric.so.1.21.0). That is not the version of the release
+// (ucx is libucp.so.0.0.0), which comes from the pkg-config file installed
+// with it, when there is one.
+func (l fabricLibrary) find(root rootfs.Root, info plugin.PluginSection) error {
+	for _, dir := range libraryDirs {
+		paths, err := root.Glob(filepath.Join(dir, l.library))
+		if err != nil {
+			return err
+		}
+		if len(paths) == 0 {
+			continue
+		}
+		sort.Strings(paths)
+		info[l.name+".path"] = plugin.String(paths[0])
+
+		// The longest name (e.g., not the libfabric.so.1 link) has the full version
+		version := ""
+		for _, path := range paths {
+			match := regexSonameVersion.FindStringSubmatch(path)
+			if match != nil && len(match[1]) > len(version) {
+				version = match[1]
+			}
+		}
+		if version != "" {
+			info[l.name+".library_version"] = plugin.Version(version)
+		}
+		raw, err := root.ReadFile(filepath.Join(filepath.Dir(paths[0]), "pkgconfig", l.pkgConfig))
+		if err == nil {
+			match := regexPkgConfigVersion.FindStringSubmatch(string(raw))
+			if match != nil {
+				info[l.name+".version"] = plugin.Version(match[1])
+			}
+		}
+		return nil
+	}
+	return nil
+}
+
+// unique returns the sorted items, without duplicates
+func unique(items []string) []string {
+	seen := map[string]bool{}
+	result := []string{}
+	for _, item := range items {
+		if !seen[item] {
+			seen[item] = true
+			result = append(result, item)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
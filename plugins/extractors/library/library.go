@@ -1,9 +1,12 @@
 package library
 
 import (
+	"context"
 	"fmt"
+	"os"
 
 	"github.com/compspec/compspec-go/pkg/plugin"
+	"github.com/compspec/compspec-go/pkg/rootfs"
 	"github.com/compspec/compspec-go/pkg/utils"
 )
 
@@ -11,12 +14,46 @@ const (
 	ExtractorName        = "library"
 	ExtractorDescription = "generic library extractor"
 	MPISection           = "mpi"
+	FabricSection        = "fabric"
+
+	// The mpirun to ask for the MPI version
+	MPIExecOption = "mpi.exec"
 )
 
 var (
-	validSections = []string{MPISection}
+	validSections = []string{MPISection, FabricSection}
+	validFields   = []plugin.FieldSpec{
+		{Section: MPISection, Name: "variant", Description: "MPI implementation (OpenMPI, mpich, or intel-mpi)", Example: "OpenMPI"},
+		{Section: MPISection, Name: "version", Type: plugin.VersionType, Description: "MPI version", Example: "4.1.1"},
+		{Section: FabricSection, Name: "libfabric.version", Type: plugin.VersionType, Description: "libfabric version", Example: "1.18.0"},
+		{Section: FabricSection, Name: "libfabric.api_version", Type: plugin.VersionType, Description: "libfabric api version", Example: "1.18"},
+		{Section: FabricSection, Name: "libfabric.providers", Type: plugin.ListType, Description: "libfabric providers from fi_info (host only)", Example: "shm tcp verbs"},
+		{Section: FabricSection, Name: "libfabric.provider.*.version", Type: plugin.VersionType, Description: "version of a libfabric provider from fi_info (host only)", Example: "118.0"},
+		{Section: FabricSection, Name: "libfabric.path", Description: "libfabric library, when fi_info is not found", Example: "/usr/lib64/libfabric.so.1"},
+		{Section: FabricSection, Name: "libfabric.library_version", Type: plugin.VersionType, Description: "version in the name of the libfabric library, when fi_info is not found", Example: "1.21.0"},
+		{Section: FabricSection, Name: "ucx.version", Type: plugin.VersionType, Description: "UCX version", Example: "1.15.0"},
+		{Section: FabricSection, Name: "ucx.transports", Type: plugin.ListType, Description: "UCX transports from ucx_info (host only)", Example: "posix rc_mlx5 self sysv tcp"},
+		{Section: FabricSection, Name: "ucx.devices", Type: plugin.ListType, Description: "UCX devices from ucx_info (host only)", Example: "eth0 memory mlx5_0:1"},
+		{Section: FabricSection, Name: "ucx.path", Description: "UCX (ucp) library, when ucx_info is not found", Example: "/usr/lib64/libucp.so.0"},
+		{Section: FabricSection, Name: "ucx.library_version", Type: plugin.VersionType, Description: "version in the name of the UCX (ucp) library, when ucx_info is not found", Example: "0.0.0"},
+	}
+	validOptions = []plugin.Option{
+		{Name: MPIExecOption, Type: plugin.StringType, Default: MPIRunExec, Description: "mpirun to get the MPI version from (a name on the PATH or a path)"},
+	}
 )
 
+// init registers the extractor so it can be found by name
+func init() {
+	plugin.Register(plugin.Registration{
+		Name:        ExtractorName,
+		Description: ExtractorDescription,
+		Kind:        plugin.ExtractorKind,
+		Sections:    validSections,
+		Options:     validOptions,
+		New:         NewPlugin,
+	})
+}
+
 type LibraryExtractor struct {
 	sections []string
 }
@@ -33,41 +70,50 @@ func (e LibraryExtractor) Description() string {
 	return ExtractorDescription
 }
 
-func (e LibraryExtractor) Create(plugin.PluginOptions) error { return nil }
-func (e LibraryExtractor) IsCreator() bool                   { return false }
-func (e LibraryExtractor) IsExtractor() bool                 { return true }
-
 // Validate ensures that the sections provided are in the list we know
 func (e LibraryExtractor) Validate() bool {
 	invalids, valid := utils.StringArrayIsSubset(e.sections, validSections)
 	for _, invalid := range invalids {
-		fmt.Printf("Sections %s is not known for extractor plugin %s\n", invalid, e.Name())
+		fmt.Fprintf(os.Stderr, "Sections %s is not known for extractor plugin %s\n", invalid, e.Name())
 	}
 	return valid
 }
 
 // Extract returns library metadata, for a set of named sections
-func (e LibraryExtractor) Extract(allowFail bool) (plugin.PluginData, error) {
-
-	sections := map[string]plugin.PluginSection{}
-	data := plugin.PluginData{}
-
-	// Only extract the sections we asked for
-	for _, name := range e.sections {
-		if name == MPISection {
-			section, err := getMPIInformation()
-			if err != nil && !allowFail {
-				return data, err
-			}
-			sections[MPISection] = section
-		}
+func (e LibraryExtractor) Extract(ctx context.Context, allowFail bool) (plugin.PluginData, error) {
+	return plugin.ExtractSections(ctx, e, e.sections, allowFail)
+}
+
+// ExtractSection returns library metadata for a single named section
+func (e LibraryExtractor) ExtractSection(ctx context.Context, name string) (plugin.PluginSection, error) {
+	switch name {
+	case MPISection:
+		mpirun := plugin.OptionsFromContext(ctx).String(MPIExecOption)
+		return getMPIInformation(ctx, rootfs.FromContext(ctx), mpirun)
+	case FabricSection:
+		return getFabricInformation(ctx, rootfs.FromContext(ctx))
+	}
+	return nil, fmt.Errorf("section %s is not known for extractor plugin %s", name, e.Name())
+}
+
+// Source returns where the data for a section comes from
+func (e LibraryExtractor) Source(name string) string {
+	switch name {
+	case MPISection:
+		return MPIRunExec + " --version (or pkg-config files for a root)"
+	case FabricSection:
+		return FiInfoExec + ", " + UCXInfoExec + " (or libfabric and ucx libraries for a root)"
 	}
-	data.Sections = sections
-	return data, nil
+	return ""
+}
+
+// Fields describes the fields that each section returns
+func (e LibraryExtractor) Fields() []plugin.FieldSpec {
+	return validFields
 }
 
 // NewPlugin validates and returns a new plugin
-func NewPlugin(sections []string) (plugin.PluginInterface, error) {
+func NewPlugin(sections []string) (plugin.Plugin, error) {
 	if len(sections) == 0 {
 		sections = validSections
 	}
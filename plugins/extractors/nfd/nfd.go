@@ -1,13 +1,18 @@
 package nfd
 
 import (
+	"context"
 	"fmt"
+	"os"
+	"strings"
+	"sync"
 
+	"github.com/converged-computing/nfd-source/pkg/utils/hostpath"
 	source "github.com/converged-computing/nfd-source/source"
 
 	// Note that "fake" is removed from here
 	_ "github.com/converged-computing/nfd-source/source/cpu"
-	_ "github.com/converged-computing/nfd-source/source/kernel"
+	"github.com/converged-computing/nfd-source/source/kernel"
 	_ "github.com/converged-computing/nfd-source/source/local"
 	_ "github.com/converged-computing/nfd-source/source/memory"
 	_ "github.com/converged-computing/nfd-source/source/network"
@@ -17,6 +22,7 @@ import (
 	_ "github.com/converged-computing/nfd-source/source/usb"
 
 	"github.com/compspec/compspec-go/pkg/plugin"
+	"github.com/compspec/compspec-go/pkg/rootfs"
 	"github.com/compspec/compspec-go/pkg/utils"
 )
 
@@ -36,9 +42,16 @@ const (
 	StorageSection = "storage"
 	SystemSection  = "system"
 	USBSection     = "usb"
+
+	// The kernel config file for the kernel source
+	KconfigOption = "kernel.kconfig"
 )
 
 var (
+	// Discovery uses global host paths, see discover
+	discoveryMutex  sync.RWMutex
+	discoveryPrefix = ""
+
 	validSections = []string{
 		CPUSection,
 		KernelSection,
@@ -50,8 +63,46 @@ var (
 		SystemSection,
 		USBSection,
 	}
+
+	// Fields are <feature>.<name> for attributes and flags, and
+	// <feature>.<index>.<name> for instances (e.g., devices)
+	validFields = []plugin.FieldSpec{
+		{Section: CPUSection, Name: "cpuid.*", Type: plugin.BoolType, Description: "a cpuid flag that the processor has", Example: "true"},
+		{Section: CPUSection, Name: "model.*", Description: "processor vendor_id, family, and id", Example: "Intel"},
+		{Section: CPUSection, Name: "topology.*", Description: "hardware_multithreading and socket_count", Example: "2"},
+		{Section: CPUSection, Name: "*", Type: plugin.AnyType, Description: "other cpu features (e.g., cstate, pstate, rdt, sgx, sst)", Example: "true"},
+		{Section: KernelSection, Name: "config.*", Description: "a kernel config option (y, m, or a value)", Example: "y"},
+		{Section: KernelSection, Name: "version.*", Description: "kernel version, as full, major, minor, and revision", Example: "6"},
+		{Section: KernelSection, Name: "loadedmodule.*", Type: plugin.BoolType, Description: "a loaded kernel module", Example: "true"},
+		{Section: KernelSection, Name: "*", Type: plugin.AnyType, Description: "other kernel features (e.g., selinux)", Example: "true"},
+		{Section: LocalSection, Name: "*", Type: plugin.AnyType, Description: "a feature from a local feature file or hook", Example: "true"},
+		{Section: MemorySection, Name: "numa.*", Description: "is_numa and node_count", Example: "2"},
+		{Section: MemorySection, Name: "*", Type: plugin.AnyType, Description: "other memory features (e.g., nv devices)", Example: "true"},
+		{Section: NetworkSection, Name: "device.*.*", Description: "an attribute (e.g., name, operstate, speed) of a physical network device", Example: "10000"},
+		{Section: NetworkSection, Name: "virtual.*.*", Description: "an attribute of a virtual network device", Example: "docker0"},
+		{Section: PCISection, Name: "device.*.*", Description: "an attribute (class, vendor, device, subsystem_vendor, subsystem_device) of a pci device", Example: "8086"},
+		{Section: StorageSection, Name: "block.*.*", Description: "an attribute (name, dax, rotational, nr_zones, zoned) of a block device", Example: "0"},
+		{Section: SystemSection, Name: "osrelease.*", Description: "a field of /etc/os-release", Example: "12"},
+		{Section: SystemSection, Name: "name.nodename", Description: "node name of the host", Example: "node-1"},
+		{Section: USBSection, Name: "device.*.*", Description: "an attribute (class, vendor, device, serial) of a usb device", Example: "1d6b"},
+	}
+	validOptions = []plugin.Option{
+		{Name: KconfigOption, Type: plugin.StringType, Description: "kernel config file for the kernel source (defaults to a search of /boot and /proc)"},
+	}
 )
 
+// init registers the extractor so it can be found by name
+func init() {
+	plugin.Register(plugin.Registration{
+		Name:        ExtractorName,
+		Description: ExtractorDescription,
+		Kind:        plugin.ExtractorKind,
+		Sections:    validSections,
+		Options:     validOptions,
+		New:         NewPlugin,
+	})
+}
+
 // NFDExtractor is an extractor for node feature discovery
 type NFDExtractor struct {
 	sections []string
@@ -69,82 +120,152 @@ func (e NFDExtractor) Description() string {
 	return ExtractorDescription
 }
 
-func (e NFDExtractor) Create(plugin.PluginOptions) error { return nil }
-func (e NFDExtractor) IsCreator() bool                   { return false }
-func (e NFDExtractor) IsExtractor() bool                 { return true }
-
 // Validate ensures that the sections provided are in the list we know
 func (e NFDExtractor) Validate() bool {
 	invalids, valid := utils.StringArrayIsSubset(e.sections, validSections)
 	for _, invalid := range invalids {
-		fmt.Printf("Sections %s is not known for extractor plugin %s\n", invalid, e.Name())
+		fmt.Fprintf(os.Stderr, "Sections %s is not known for extractor plugin %s\n", invalid, e.Name())
 	}
 	return valid
 }
 
-// Extract returns system metadata, for a set of named sections
-func (e NFDExtractor) Extract(allowFail bool) (plugin.PluginData, error) {
+// discover runs discovery for a source with host paths under a root.
+// The host paths are global to nfd, so discovery for the same root can
+// run at once, and we wait for it to finish to change the root.
+func discover(discovery source.FeatureSource, root rootfs.Root) error {
+	prefix := strings.TrimSuffix(root.String(), "/")
+	for {
+		discoveryMutex.RLock()
+		if discoveryPrefix == prefix {
+			break
+		}
+		discoveryMutex.RUnlock()
+
+		discoveryMutex.Lock()
+		hostpath.BootDir = hostpath.HostDir(prefix + "/boot")
+		hostpath.EtcDir = hostpath.HostDir(prefix + "/etc")
+		hostpath.SysfsDir = hostpath.HostDir(prefix + "/sys")
+		hostpath.UsrDir = hostpath.HostDir(prefix + "/usr")
+		hostpath.VarDir = hostpath.HostDir(prefix + "/var")
+		hostpath.LibDir = hostpath.HostDir(prefix + "/lib")
+		discoveryPrefix = prefix
+		discoveryMutex.Unlock()
+	}
+	defer discoveryMutex.RUnlock()
+	return discovery.Discover()
+}
+
+// configureKernel sets the kernel config file for the kernel source,
+// which is global, so an empty path goes back to the default (a search)
+func configureKernel(discovery source.FeatureSource, root rootfs.Root, path string) error {
+	configurable, ok := discovery.(source.ConfigurableSource)
+	if !ok {
+		return nil
+	}
+	config, ok := configurable.NewConfig().(*kernel.Config)
+	if !ok {
+		return nil
+	}
+	if path != "" {
+		kconfig, err := root.Path(path)
+		if err != nil {
+			return err
+		}
+		config.KconfigFile = kconfig
+	}
+	configurable.SetConfig(config)
+	return nil
+}
+
+// HostOnly is true for all sections, which describe hardware and the kernel
+func (e NFDExtractor) HostOnly(name string) bool {
+	return true
+}
+
+// Source returns where the data for a section comes from
+func (e NFDExtractor) Source(name string) string {
+	return fmt.Sprintf("nfd %s feature source", name)
+}
+
+// Extract returns node feature discovery metadata, for a set of named sections
+func (e NFDExtractor) Extract(ctx context.Context, allowFail bool) (plugin.PluginData, error) {
+	return plugin.ExtractSections(ctx, e, e.sections, allowFail)
+}
 
-	sections := map[string]plugin.PluginSection{}
-	data := plugin.PluginData{}
+// ExtractSection returns node feature discovery metadata for a single source.
+// Discovery cannot be cancelled, so a timeout is handled by plugin.ExtractSection
+func (e NFDExtractor) ExtractSection(ctx context.Context, name string) (plugin.PluginSection, error) {
 
 	// Get all registered feature sources
 	sources := source.GetAllFeatureSources()
+	discovery, ok := sources[name]
 
-	// Only extract the sections we asked for
-	for _, name := range e.sections {
-		discovery, ok := sources[name]
+	// This should not happen
+	if !ok {
+		return nil, fmt.Errorf("%s is not a known feature source", name)
+	}
 
-		// This should not happen
-		if !ok {
-			fmt.Printf("%s is not a known feature source\n", name)
-			continue
-		}
-		err := discovery.Discover()
+	// CPU features come from the processor (cpuid) and not from files
+	root := rootfs.FromContext(ctx)
+	if name == CPUSection && !root.IsHost() {
+		return nil, plugin.Unsupported("cpu features cannot be discovered for root %s", root)
+	}
+
+	// The kernel source can be told where the kernel config is
+	if name == KernelSection {
+		err := configureKernel(discovery, root, plugin.OptionsFromContext(ctx).String(KconfigOption))
 		if err != nil {
-			fmt.Printf("Issue discovering features for %s\n", discovery.Name())
-			continue
+			return nil, err
 		}
+	}
 
-		// Create a new section for the <name> group
-		// For each of the below, "fs" is a feature set
-		// AttributeFeatureSet
-		section := plugin.PluginSection{}
-		features := discovery.GetFeatures()
-		for k, fs := range features.Attributes {
-			for fName, feature := range fs.Elements {
-				uid := fmt.Sprintf("%s.%s", k, fName)
-				section[uid] = feature
-			}
+	// A source that cannot discover features is usually not on this system
+	err := discover(discovery, root)
+	if err != nil {
+		return nil, plugin.Unsupported("issue discovering features for %s: %s", discovery.Name(), err)
+	}
+
+	// Create a new section for the <name> group
+	// For each of the below, "fs" is a feature set
+	// AttributeFeatureSet
+	section := plugin.PluginSection{}
+	features := discovery.GetFeatures()
+	for k, fs := range features.Attributes {
+		for fName, feature := range fs.Elements {
+			uid := fmt.Sprintf("%s.%s", k, fName)
+			section[uid] = plugin.String(feature)
 		}
+	}
 
-		// FlagFeatureSet
-		// Note that the second value to feature is v1alpha.Nil
-		// I think this acts as a flag, double check
-		for k, fs := range features.Flags {
-			for feature, _ := range fs.Elements {
-				uid := fmt.Sprintf("%s.%s", k, feature)
-				section[uid] = "true"
-			}
+	// FlagFeatureSet
+	// Note that the second value to feature is v1alpha.Nil
+	// I think this acts as a flag, double check
+	for k, fs := range features.Flags {
+		for feature, _ := range fs.Elements {
+			uid := fmt.Sprintf("%s.%s", k, feature)
+			section[uid] = plugin.Bool(true)
 		}
+	}
 
-		// InstanceFeatureSet
-		for k, fs := range features.Instances {
-			for idx, feature := range fs.Elements {
-				for fName, attr := range feature.Attributes {
-					uid := fmt.Sprintf("%s.%d.%s", k, idx, fName)
-					section[uid] = attr
-				}
+	// InstanceFeatureSet
+	for k, fs := range features.Instances {
+		for idx, feature := range fs.Elements {
+			for fName, attr := range feature.Attributes {
+				uid := fmt.Sprintf("%s.%d.%s", k, idx, fName)
+				section[uid] = plugin.String(attr)
 			}
 		}
-		sections[name] = section
 	}
-	data.Sections = sections
-	return data, nil
+	return section, nil
+}
+
+// Fields describes the fields that each section returns
+func (e NFDExtractor) Fields() []plugin.FieldSpec {
+	return validFields
 }
 
 // NewPlugin validates and returns a new kernel plugin
-func NewPlugin(sections []string) (plugin.PluginInterface, error) {
+func NewPlugin(sections []string) (plugin.Plugin, error) {
 	if len(sections) == 0 {
 		sections = validSections
 	}
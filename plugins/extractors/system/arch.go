@@ -1,10 +1,12 @@
 package system
 
 import (
+	"context"
 	"fmt"
 	"os/exec"
 
 	"github.com/compspec/compspec-go/pkg/plugin"
+	"github.com/compspec/compspec-go/pkg/rootfs"
 	"github.com/compspec/compspec-go/pkg/utils"
 )
 
@@ -19,11 +21,11 @@ var (
 )
 
 // getOSArch determines arch based on the ld linux path
-func getOsArch() (string, error) {
+func getOsArch(root rootfs.Root) (string, error) {
 
 	// Detect OS architecture based on presence of this file
 	for arch, path := range linkerPaths {
-		exists, err := utils.PathExists(path)
+		exists, err := root.Exists(path)
 		if err != nil {
 			return "", err
 		}
@@ -35,22 +37,27 @@ func getOsArch() (string, error) {
 }
 
 // getArchInformation gets architecture information
-func getArchInformation() (plugin.PluginSection, error) {
+func getArchInformation(ctx context.Context, root rootfs.Root) (plugin.PluginSection, error) {
 	info := plugin.PluginSection{}
 
 	// Read in architectures
-	arch, err := getOsArch()
+	arch, err := getOsArch(root)
 	if err != nil {
 		return info, err
 	}
-	info["name"] = arch
+	info["name"] = plugin.String(arch)
+
+	// The arch command describes the host, so we can't use it for another root
+	if !root.IsHost() {
+		return info, nil
+	}
 
 	// Try to run arch command to get more details, OK if we don't have it
 	path, err := exec.LookPath("arch")
 	if err == nil {
-		output, err := utils.RunCommand([]string{path})
+		output, err := utils.RunCommandContext(ctx, []string{path})
 		if err == nil {
-			info["arch"] = output
+			info["arch"] = plugin.String(output)
 		}
 	}
 	return info, nil
@@ -7,6 +7,7 @@ import (
 	"strings"
 
 	"github.com/compspec/compspec-go/pkg/plugin"
+	"github.com/compspec/compspec-go/pkg/rootfs"
 	"github.com/compspec/compspec-go/pkg/utils"
 )
 
@@ -114,13 +115,27 @@ func getCpuFeatures(p map[string]string) (string, error) {
 
 // getCPUInformation gets information about the system
 // TODO this is not used.
-func getCPUInformation() (plugin.PluginSection, error) {
+func getCPUInformation(root rootfs.Root) (plugin.PluginSection, error) {
 	info := plugin.PluginSection{}
 
 	cores := runtime.NumCPU()
 
+	// For another root, count the processors that it describes
+	if !root.IsHost() {
+		raw, err := root.ReadFile(CpuInfoFile)
+		if err != nil {
+			return nil, err
+		}
+		cores = 0
+		for _, line := range strings.Split(string(raw), "\n") {
+			if strings.HasPrefix(line, "processor") {
+				cores += 1
+			}
+		}
+	}
+
 	// This is a guess at best
-	info["cores"] = fmt.Sprintf("%d", cores)
+	info["cores"] = plugin.Int(int64(cores))
 
 	//stat, err := linuxproc.ReadCPUInfo(CpuInfoFile)
 	//if err != nil {
@@ -136,10 +151,10 @@ func getCPUInformation() (plugin.PluginSection, error) {
 }
 
 // getProcessorInformation returns details about each processor
-func getProcessorInformation() (plugin.PluginSection, error) {
+func getProcessorInformation(root rootfs.Root) (plugin.PluginSection, error) {
 	info := plugin.PluginSection{}
 
-	raw, err := os.ReadFile(CpuInfoFile)
+	raw, err := root.ReadFile(CpuInfoFile)
 	if err != nil {
 		return nil, err
 	}
@@ -188,7 +203,7 @@ func getProcessorInformation() (plugin.PluginSection, error) {
 	for i, p := range processors {
 		uid, ok := p["processor"]
 		if !ok {
-			fmt.Printf("Warning, processor metadata index %d missing 'processor' uid, skipping\n", i)
+			fmt.Fprintf(os.Stderr, "Warning, processor metadata index %d missing 'processor' uid, skipping\n", i)
 			continue
 		}
 		uid = fmt.Sprintf("%s.", uid)
@@ -196,40 +211,44 @@ func getProcessorInformation() (plugin.PluginSection, error) {
 		// Parse cpu vendor - arm has a lookup
 		vendor, err := getCpuVendor(p)
 		if err == nil {
-			info[uid+"normalized.vendor"] = vendor
+			info[uid+"normalized.vendor"] = plugin.String(vendor)
 		}
 
 		// bogompis should be the same after lowercase
 		bogomips, ok := p["bogomips"]
 		if ok {
-			info[uid+"normalized.botomips"] = bogomips
+			value, err := plugin.ParseValue(bogomips, plugin.FloatType)
+			if err != nil {
+				value = plugin.String(bogomips)
+			}
+			info[uid+"normalized.botomips"] = value
 		}
 
 		// features or flags
 		features, err := getCpuFeatures(p)
 		if err == nil {
-			info[uid+"normalized.features"] = features
+			info[uid+"normalized.features"] = plugin.List(strings.Fields(features))
 		}
 
 		family, err := getCpuArchitecture(p)
 		if err == nil {
-			info[uid+"normalized.family"] = family
+			info[uid+"normalized.family"] = plugin.String(family)
 		}
 
 		variant, err := getCpuVariant(p)
 		if err == nil {
-			info[uid+"normalized.model"] = variant
+			info[uid+"normalized.model"] = plugin.String(variant)
 		}
 		if isPPC {
 			for key, value := range ppcFields {
 				if key == "model" {
 					key = "normalized.model"
 				}
-				info[uid+key] = value
+				info[uid+key] = plugin.String(value)
 			}
 		}
 		for key, value := range p {
-			info[uid+"raw."+key] = value
+			info[uid+"raw."+key] = plugin.String(value)
 		}
 
 	}
@@ -0,0 +1,120 @@
+package system
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/compspec/compspec-go/pkg/plugin"
+	"github.com/compspec/compspec-go/pkg/rootfs"
+)
+
+const (
+	hugepagesDir            = "/sys/kernel/mm/hugepages"
+	transparentHugepagesDir = "/sys/kernel/mm/transparent_hugepage"
+)
+
+var (
+	// Counts for a pool of huge pages, and what we call them
+	poolFields = []struct{ file, name string }{
+		{"nr_hugepages", "total"},
+		{"free_hugepages", "free"},
+		{"resv_hugepages", "reserved"},
+		{"surplus_hugepages", "surplus"},
+		{"nr_overcommit_hugepages", "overcommit"},
+	}
+
+	// Settings of transparent huge pages, where the current one is in
+	// brackets (always [madvise] never)
+	transparentSettings = []string{"enabled", "defrag", "shmem_enabled"}
+	regexSelected       = regexp.MustCompile(`\[([^\]]+)\]`)
+)
+
+// getHugepagesInformation describes the pools of huge pages for each size,
+// for the system and each numa node, and transparent huge pages
+func getHugepagesInformation(root rootfs.Root) (plugin.PluginSection, error) {
+	info := plugin.PluginSection{}
+
+	// Pools are named by size (e.g., hugepages-2048kB)
+	pools, err := root.Glob(filepath.Join(hugepagesDir, "hugepages-*"))
+	if err != nil {
+		return nil, err
+	}
+	sizes := []string{}
+	for _, path := range pools {
+		size := strings.TrimPrefix(filepath.Base(path), "hugepages-")
+		sizes = append(sizes, size)
+		addPool(root, path, "pool."+size+".", info)
+	}
+	if len(sizes) > 0 {
+		info["sizes"] = plugin.List(sizes)
+	}
+
+	// The default size is only in /proc/meminfo
+	raw, err := root.ReadFile(memoryInfoFile)
+	if err == nil {
+		for _, line := range strings.Split(string(raw), "\n") {
+			key, value, ok := strings.Cut(line, ":")
+			if !ok || key != "Hugepagesize" {
+				continue
+			}
+			quantity, err := plugin.ParseBytes(value)
+			if err == nil {
+				info["default_size"] = quantity
+			}
+		}
+	}
+
+	// Pools for each numa node
+	nodes, err := getNumaNodes(root)
+	if err != nil {
+		return nil, err
+	}
+	for _, node := range nodes {
+		for _, size := range sizes {
+			path := filepath.Join(nodeDevicesDir, fmt.Sprintf("node%d", node), "hugepages", "hugepages-"+size)
+			addPool(root, path, fmt.Sprintf("node.%d.pool.%s.", node, size), info)
+		}
+	}
+
+	// Transparent huge pages, and the size of one
+	for _, name := range transparentSettings {
+		match := regexSelected.FindStringSubmatch(readString(root, filepath.Join(transparentHugepagesDir, name)))
+		if match != nil {
+			info["transparent."+name] = plugin.String(match[1])
+		}
+	}
+	size, err := readInt(root, filepath.Join(transparentHugepagesDir, "hpage_pmd_size"))
+	if err == nil {
+		info["transparent.size"] = plugin.Bytes(size, "B")
+	}
+
+	// Newer kernels can enable transparent huge pages of each size (mTHP)
+	sizedPaths, err := root.Glob(filepath.Join(transparentHugepagesDir, "hugepages-*"))
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range sizedPaths {
+		size := strings.TrimPrefix(filepath.Base(path), "hugepages-")
+		match := regexSelected.FindStringSubmatch(readString(root, filepath.Join(path, "enabled")))
+		if match != nil {
+			info["transparent."+size+".enabled"] = plugin.String(match[1])
+		}
+	}
+
+	if len(info) == 0 {
+		return nil, plugin.Skipped("%s and %s are not found", hugepagesDir, transparentHugepagesDir)
+	}
+	return info, nil
+}
+
+// addPool adds the counts for a pool of huge pages that exist
+func addPool(root rootfs.Root, path, prefix string, info plugin.PluginSection) {
+	for _, field := range poolFields {
+		count, err := readInt(root, filepath.Join(path, field.file))
+		if err == nil {
+			info[prefix+field.name] = plugin.Int(count)
+		}
+	}
+}
@@ -1,10 +1,10 @@
 package system
 
 import (
-	"os"
 	"strings"
 
 	"github.com/compspec/compspec-go/pkg/plugin"
+	"github.com/compspec/compspec-go/pkg/rootfs"
 )
 
 const (
@@ -12,10 +12,10 @@ const (
 )
 
 // getMemoryInformation parses /proc/meminfo to get node memory metadata
-func getMemoryInformation() (plugin.PluginSection, error) {
+func getMemoryInformation(root rootfs.Root) (plugin.PluginSection, error) {
 	info := plugin.PluginSection{}
 
-	raw, err := os.ReadFile(memoryInfoFile)
+	raw, err := root.ReadFile(memoryInfoFile)
 	if err != nil {
 		return nil, err
 	}
@@ -35,7 +35,14 @@ func getMemoryInformation() (plugin.PluginSection, error) {
 		// Replace parens with underscore. Leave camel case for the rest...
 		key = strings.ReplaceAll(key, "(", "_")
 		key = strings.ToLower(strings.ReplaceAll(key, ")", ""))
-		info[key] = value
+
+		// Most values are sizes (e.g., 16318036 kB) and the rest are counts
+		quantity, err := plugin.ParseBytes(value)
+		if err == nil && quantity.Type() == plugin.BytesType && strings.Contains(value, " ") {
+			info[key] = quantity
+		} else {
+			info[key] = plugin.InferValue(value)
+		}
 	}
 	return info, nil
 }
@@ -0,0 +1,99 @@
+package system
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/compspec/compspec-go/pkg/plugin"
+	"github.com/compspec/compspec-go/pkg/rootfs"
+	"github.com/compspec/compspec-go/pkg/utils"
+)
+
+const (
+	nodeDevicesDir = "/sys/devices/system/node"
+)
+
+var (
+	// Memory of a numa node (Node 0 MemTotal: 5078776 kB) that we keep
+	nodeMemoryFields = map[string]string{
+		"MemTotal": "memtotal",
+		"MemFree":  "memfree",
+		"MemUsed":  "memused",
+	}
+)
+
+// getNumaNodes returns the numbers of the numa nodes, sorted
+func getNumaNodes(root rootfs.Root) ([]int, error) {
+	paths, err := root.Glob(filepath.Join(nodeDevicesDir, "node[0-9]*"))
+	if err != nil {
+		return nil, err
+	}
+	nodes := []int{}
+	for _, path := range paths {
+		node, err := strconv.Atoi(strings.TrimPrefix(filepath.Base(path), "node"))
+		if err == nil {
+			nodes = append(nodes, node)
+		}
+	}
+	sort.Ints(nodes)
+	return nodes, nil
+}
+
+// getNumaInformation describes each numa node, including its cpus, memory,
+// and distance to every node (in the order of the node numbers)
+func getNumaInformation(root rootfs.Root) (plugin.PluginSection, error) {
+	nodes, err := getNumaNodes(root)
+	if err != nil {
+		return nil, err
+	}
+	if len(nodes) == 0 {
+		return nil, plugin.Skipped("no numa nodes were found in %s", nodeDevicesDir)
+	}
+	info := plugin.PluginSection{}
+	for _, name := range []string{"online", "possible"} {
+		list, err := readCPUList(root, filepath.Join(nodeDevicesDir, name))
+		if err == nil {
+			info[name] = plugin.String(utils.FormatCPUList(list))
+		}
+	}
+	info["nodes"] = plugin.Int(int64(len(nodes)))
+
+	for _, node := range nodes {
+		path := filepath.Join(nodeDevicesDir, fmt.Sprintf("node%d", node))
+		prefix := fmt.Sprintf("node.%d.", node)
+
+		cpus, err := readCPUList(root, filepath.Join(path, "cpulist"))
+		if err == nil {
+			info[prefix+"cpus"] = plugin.String(utils.FormatCPUList(cpus))
+		}
+		distances := strings.Fields(readString(root, filepath.Join(path, "distance")))
+		if len(distances) > 0 {
+			info[prefix+"distances"] = plugin.List(distances)
+		}
+
+		raw, err := root.ReadFile(filepath.Join(path, "meminfo"))
+		if err != nil {
+			continue
+		}
+		for _, line := range strings.Split(string(raw), "\n") {
+
+			// Node 0 MemTotal:        5078776 kB
+			parts := strings.Fields(line)
+			if len(parts) < 4 {
+				continue
+			}
+			key, ok := nodeMemoryFields[strings.TrimSuffix(parts[2], ":")]
+			if !ok {
+				continue
+			}
+			quantity, err := plugin.ParseBytes(strings.Join(parts[3:], " "))
+			if err == nil {
+				info[prefix+key] = quantity
+			}
+		}
+	}
+	return info, nil
+}
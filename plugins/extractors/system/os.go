@@ -7,11 +7,11 @@ package system
 import (
 	"bufio"
 	"fmt"
-	"os"
 	"regexp"
 	"strings"
 
 	"github.com/compspec/compspec-go/pkg/plugin"
+	"github.com/compspec/compspec-go/pkg/rootfs"
 )
 
 const (
@@ -33,12 +33,12 @@ var (
 )
 
 // readOsRelease gets the name, version, and vendor from the os release file
-func parseOsRelease() (string, string, string, error) {
+func parseOsRelease(root rootfs.Root) (string, string, string, error) {
 
 	var name, version, vendor string
 
 	// Determine OS release by reading this file
-	f, err := os.Open(osReleaseFile)
+	f, err := root.Open(osReleaseFile)
 	if err != nil {
 		return name, version, vendor, fmt.Errorf("cannot find %s to determine OS metadata and release", osReleaseFile)
 	}
@@ -72,11 +72,11 @@ func parseOsRelease() (string, string, string, error) {
 }
 
 // readOsRelease looks for different os version files to read
-func readOsRelease(prettyName string, vendor string) (string, error) {
+func readOsRelease(root rootfs.Root, prettyName string, vendor string) (string, error) {
 
 	switch vendor {
 	case "debian":
-		raw, err := os.ReadFile(versionDebianFile)
+		raw, err := root.ReadFile(versionDebianFile)
 		if err != nil {
 			return "", err
 		}
@@ -87,7 +87,7 @@ func readOsRelease(prettyName string, vendor string) (string, error) {
 			return match[1], nil
 		}
 	case "centos":
-		raw, err := os.ReadFile(versionCentosFile)
+		raw, err := root.ReadFile(versionCentosFile)
 		if err != nil {
 			return "", err
 		}
@@ -96,7 +96,7 @@ func readOsRelease(prettyName string, vendor string) (string, error) {
 			return match[2], nil
 		}
 	case "rocky":
-		raw, err := os.ReadFile(versionRockyFile)
+		raw, err := root.ReadFile(versionRockyFile)
 		if err != nil {
 			return "", err
 		}
@@ -107,7 +107,7 @@ func readOsRelease(prettyName string, vendor string) (string, error) {
 			return parts[0], nil
 		}
 	case "rhel":
-		raw, err := os.ReadFile(versionRHELFile)
+		raw, err := root.ReadFile(versionRHELFile)
 		if err != nil {
 			return "", err
 		}
@@ -124,23 +124,23 @@ func readOsRelease(prettyName string, vendor string) (string, error) {
 }
 
 // getOSInformation gets operating system level metadata
-func getOsInformation() (plugin.PluginSection, error) {
+func getOsInformation(root rootfs.Root) (plugin.PluginSection, error) {
 	info := plugin.PluginSection{}
 
 	// Get the name, version, and vendor
-	name, version, vendor, err := parseOsRelease()
+	name, version, vendor, err := parseOsRelease(root)
 	if err != nil {
 		return info, err
 	}
-	info["name"] = name
-	info["version"] = version
-	info["vendor"] = vendor
+	info["name"] = plugin.String(name)
+	info["version"] = plugin.Version(version)
+	info["vendor"] = plugin.String(vendor)
 
 	// Read in the os release metadata
-	osRelease, err := readOsRelease(name, vendor)
+	osRelease, err := readOsRelease(root, name, vendor)
 	if err != nil {
 		return info, err
 	}
-	info["release"] = osRelease
+	info["release"] = plugin.String(osRelease)
 	return info, nil
 }
@@ -1,9 +1,12 @@
 package system
 
 import (
+	"context"
 	"fmt"
+	"os"
 
 	"github.com/compspec/compspec-go/pkg/plugin"
+	"github.com/compspec/compspec-go/pkg/rootfs"
 	"github.com/compspec/compspec-go/pkg/utils"
 )
 
@@ -17,12 +20,80 @@ const (
 	ArchSection      = "arch"
 	OsSection        = "os"
 	MemorySection    = "memory"
+	TopologySection  = "topology"
+	NumaSection      = "numa"
+	HugepagesSection = "hugepages"
 )
 
 var (
-	validSections = []string{ProcessorSection, OsSection, ArchSection, MemorySection, CPUSection}
+	validSections = []string{ProcessorSection, OsSection, ArchSection, MemorySection, CPUSection, TopologySection, NumaSection, HugepagesSection}
+
+	// Fields for each section, where * is a processor, socket, core, node, size, or any name
+	validFields = []plugin.FieldSpec{
+		{Section: ProcessorSection, Name: "*.normalized.vendor", Description: "processor vendor (an ARM implementer is named)", Example: "GenuineIntel"},
+		{Section: ProcessorSection, Name: "*.normalized.botomips", Type: plugin.FloatType, Description: "bogomips of the processor", Example: "4992.00"},
+		{Section: ProcessorSection, Name: "*.normalized.features", Type: plugin.ListType, Description: "processor flags (x86) or features (ARM)", Example: "fpu vme sse sse2"},
+		{Section: ProcessorSection, Name: "*.normalized.family", Description: "cpu family (x86) or architecture (ARM)", Example: "6"},
+		{Section: ProcessorSection, Name: "*.normalized.model", Description: "model name (x86) or variant (ARM)", Example: "13th Gen Intel(R) Core(TM) i5-1335U"},
+		{Section: ProcessorSection, Name: "*.raw.*", Description: "a field of the processor in /proc/cpuinfo as is (lowercase, with _ for spaces)", Example: "2500.000"},
+		{Section: OsSection, Name: "name", Description: "pretty name of the operating system", Example: "Debian GNU/Linux 12 (bookworm)"},
+		{Section: OsSection, Name: "version", Type: plugin.VersionType, Description: "version id of the operating system", Example: "12"},
+		{Section: OsSection, Name: "vendor", Description: "id of the operating system", Example: "debian"},
+		{Section: OsSection, Name: "release", Description: "release of the operating system", Example: "12.12"},
+		{Section: ArchSection, Name: "name", Description: "architecture of the dynamic linker", Example: "amd64"},
+		{Section: ArchSection, Name: "arch", Description: "machine architecture from the arch command (host only)", Example: "x86_64"},
+		{Section: MemorySection, Name: "memtotal", Type: plugin.BytesType, Unit: "bytes", Description: "total usable memory", Example: "16318036 kB"},
+		{Section: MemorySection, Name: "hugepages_total", Type: plugin.IntType, Description: "number of huge pages", Example: "0"},
+		{Section: MemorySection, Name: "*", Type: plugin.AnyType, Description: "a field of /proc/meminfo (lowercase), a size (bytes) or a count", Example: "4210544 kB"},
+		{Section: CPUSection, Name: "cores", Type: plugin.IntType, Description: "number of logical processors", Example: "12"},
+		{Section: TopologySection, Name: "sockets", Type: plugin.IntType, Description: "number of sockets (physical packages)", Example: "2"},
+		{Section: TopologySection, Name: "cores", Type: plugin.IntType, Description: "number of physical cores", Example: "64"},
+		{Section: TopologySection, Name: "threads", Type: plugin.IntType, Description: "number of hardware threads (online cpus)", Example: "128"},
+		{Section: TopologySection, Name: "threads_per_core", Type: plugin.IntType, Description: "hardware threads for each core", Example: "2"},
+		{Section: TopologySection, Name: "online", Description: "cpus that are online, as a cpu list", Example: "0-127"},
+		{Section: TopologySection, Name: "possible", Description: "cpus that could be brought online, as a cpu list", Example: "0-127"},
+		{Section: TopologySection, Name: "present", Description: "cpus that are present, as a cpu list", Example: "0-127"},
+		{Section: TopologySection, Name: "allowed", Description: "cpus that compspec is allowed to run on (host only), as a cpu list", Example: "0-63"},
+		{Section: TopologySection, Name: "socket.*.cpus", Description: "cpus in a socket, as a cpu list", Example: "0-31,64-95"},
+		{Section: TopologySection, Name: "socket.*.cores", Type: plugin.IntType, Description: "number of physical cores in a socket", Example: "32"},
+		{Section: TopologySection, Name: "socket.*.core.*.siblings", Description: "hardware threads of a core, as a cpu list", Example: "0,64"},
+		{Section: TopologySection, Name: "cache.*.count", Type: plugin.IntType, Description: "number of caches at a level (L1d, L1i, L2, L3), or of one size at a level", Example: "64"},
+		{Section: TopologySection, Name: "cache.*.sizes", Type: plugin.ListType, Description: "distinct sizes of the caches at a level, smallest first", Example: "1280K 2048K"},
+		{Section: TopologySection, Name: "cache.*.*.size", Type: plugin.BytesType, Unit: "bytes", Description: "size of one cache at a level, which is in the field name (e.g., cache.L2.2048K.size)", Example: "2048 K"},
+		{Section: TopologySection, Name: "cache.*.*.cpus", Description: "cpus with a cache of a size at a level, as a cpu list", Example: "0-15"},
+		{Section: TopologySection, Name: "cache.*.*.shared_by", Type: plugin.IntType, Description: "most cpus that share one cache of a size at a level", Example: "2"},
+		{Section: TopologySection, Name: "cache.*.*.line_size", Type: plugin.IntType, Unit: "bytes", Description: "coherency line size of a cache of a size at a level", Example: "64"},
+		{Section: NumaSection, Name: "nodes", Type: plugin.IntType, Description: "number of numa nodes", Example: "2"},
+		{Section: NumaSection, Name: "online", Description: "numa nodes that are online, as a list", Example: "0-1"},
+		{Section: NumaSection, Name: "possible", Description: "numa nodes that could be brought online, as a list", Example: "0-1"},
+		{Section: NumaSection, Name: "node.*.cpus", Description: "cpus of a numa node, as a cpu list", Example: "0-31,64-95"},
+		{Section: NumaSection, Name: "node.*.distances", Type: plugin.ListType, Description: "distance from a numa node to each node, in order", Example: "10 21"},
+		{Section: NumaSection, Name: "node.*.memtotal", Type: plugin.BytesType, Unit: "bytes", Description: "total memory of a numa node", Example: "263842304 kB"},
+		{Section: NumaSection, Name: "node.*.memfree", Type: plugin.BytesType, Unit: "bytes", Description: "free memory of a numa node", Example: "250123456 kB"},
+		{Section: NumaSection, Name: "node.*.memused", Type: plugin.BytesType, Unit: "bytes", Description: "used memory of a numa node", Example: "13718848 kB"},
+		{Section: HugepagesSection, Name: "sizes", Type: plugin.ListType, Description: "sizes of the huge page pools", Example: "1048576kB 2048kB"},
+		{Section: HugepagesSection, Name: "default_size", Type: plugin.BytesType, Unit: "bytes", Description: "default size of a huge page", Example: "2048 kB"},
+		{Section: HugepagesSection, Name: "pool.*", Type: plugin.IntType, Description: "huge pages in the pool of a size (e.g., pool.2048kB.total) that are total, free, reserved, surplus, or overcommit", Example: "1024"},
+		{Section: HugepagesSection, Name: "node.*.pool.*", Type: plugin.IntType, Description: "huge pages in the pool of a size on a numa node (e.g., node.0.pool.2048kB.free) that are total, free, or surplus", Example: "512"},
+		{Section: HugepagesSection, Name: "transparent.enabled", Description: "when transparent huge pages are used (always, madvise, or never)", Example: "madvise"},
+		{Section: HugepagesSection, Name: "transparent.defrag", Description: "when transparent huge pages are defragmented", Example: "madvise"},
+		{Section: HugepagesSection, Name: "transparent.shmem_enabled", Description: "when transparent huge pages are used for shared memory", Example: "never"},
+		{Section: HugepagesSection, Name: "transparent.size", Type: plugin.BytesType, Unit: "bytes", Description: "size of a transparent huge page", Example: "2097152 B"},
+		{Section: HugepagesSection, Name: "transparent.*.enabled", Description: "when transparent huge pages of a size are used (mTHP)", Example: "inherit"},
+	}
 )
 
+// init registers the extractor so it can be found by name
+func init() {
+	plugin.Register(plugin.Registration{
+		Name:        ExtractorName,
+		Description: ExtractorDescription,
+		Kind:        plugin.ExtractorKind,
+		Sections:    validSections,
+		New:         NewPlugin,
+	})
+}
+
 type SystemExtractor struct {
 	sections []string
 }
@@ -39,72 +110,80 @@ func (e SystemExtractor) Sections() []string {
 	return e.sections
 }
 
-func (e SystemExtractor) Create(plugin.PluginOptions) error { return nil }
-func (e SystemExtractor) IsCreator() bool                   { return false }
-func (e SystemExtractor) IsExtractor() bool                 { return true }
-
 // Validate ensures that the sections provided are in the list we know
 func (e SystemExtractor) Validate() bool {
 	invalids, valid := utils.StringArrayIsSubset(e.sections, validSections)
 	for _, invalid := range invalids {
-		fmt.Printf("Sections %s is not known for extractor plugin %s\n", invalid, e.Name())
+		fmt.Fprintf(os.Stderr, "Sections %s is not known for extractor plugin %s\n", invalid, e.Name())
 	}
 	return valid
 }
 
 // Extract returns system metadata, for a set of named sections
-func (e SystemExtractor) Extract(allowFail bool) (plugin.PluginData, error) {
-
-	sections := map[string]plugin.PluginSection{}
-	data := plugin.PluginData{}
-
-	// Only extract the sections we asked for
-	for _, name := range e.sections {
-		if name == ProcessorSection {
-			section, err := getProcessorInformation()
-			if err != nil && !allowFail {
-				return data, err
-			}
-			sections[ProcessorSection] = section
-		}
-		if name == OsSection {
-			section, err := getOsInformation()
-			if err != nil && !allowFail {
-				return data, err
-			}
-			sections[OsSection] = section
-		}
-
-		if name == CPUSection {
-			section, err := getCPUInformation()
-			if err != nil && !allowFail {
-				return data, err
-			}
-			sections[CPUSection] = section
-		}
-		if name == ArchSection {
-			section, err := getArchInformation()
-			if err != nil && !allowFail {
-				return data, err
-			}
-			sections[ArchSection] = section
-		}
-
-		if name == MemorySection {
-			section, err := getMemoryInformation()
-			if err != nil && !allowFail {
-				return data, err
-			}
-			sections[MemorySection] = section
-		}
+func (e SystemExtractor) Extract(ctx context.Context, allowFail bool) (plugin.PluginData, error) {
+	return plugin.ExtractSections(ctx, e, e.sections, allowFail)
+}
+
+// ExtractSection returns system metadata for a single named section
+func (e SystemExtractor) ExtractSection(ctx context.Context, name string) (plugin.PluginSection, error) {
+	root := rootfs.FromContext(ctx)
+	switch name {
+	case ProcessorSection:
+		return getProcessorInformation(root)
+	case OsSection:
+		return getOsInformation(root)
+	case CPUSection:
+		return getCPUInformation(root)
+	case ArchSection:
+		return getArchInformation(ctx, root)
+	case MemorySection:
+		return getMemoryInformation(root)
+	case TopologySection:
+		return getTopologyInformation(root)
+	case NumaSection:
+		return getNumaInformation(root)
+	case HugepagesSection:
+		return getHugepagesInformation(root)
+	}
+	return nil, fmt.Errorf("section %s is not known for extractor plugin %s", name, e.Name())
+}
+
+// HostOnly is true for sections that describe hardware. The operating
+// system and architecture come from files that an image has.
+func (e SystemExtractor) HostOnly(name string) bool {
+	return name != OsSection && name != ArchSection
+}
 
+// Source returns where the data for a section comes from
+func (e SystemExtractor) Source(name string) string {
+	switch name {
+	case ProcessorSection:
+		return CpuInfoFile
+	case OsSection:
+		return osReleaseFile
+	case CPUSection:
+		return "runtime"
+	case ArchSection:
+		return "dynamic linker, arch"
+	case MemorySection:
+		return memoryInfoFile
+	case TopologySection:
+		return cpuDevicesDir
+	case NumaSection:
+		return nodeDevicesDir
+	case HugepagesSection:
+		return hugepagesDir + ", " + transparentHugepagesDir
 	}
-	data.Sections = sections
-	return data, nil
+	return ""
+}
+
+// Fields describes the fields that each section returns
+func (e SystemExtractor) Fields() []plugin.FieldSpec {
+	return validFields
 }
 
 // NewPlugin validates and returns a new kernel plugin
-func NewPlugin(sections []string) (plugin.PluginInterface, error) {
+func NewPlugin(sections []string) (plugin.Plugin, error) {
 	if len(sections) == 0 {
 		sections = validSections
 	}
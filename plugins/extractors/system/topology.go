@@ -0,0 +1,255 @@
+package system
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/compspec/compspec-go/pkg/plugin"
+	"github.com/compspec/compspec-go/pkg/rootfs"
+	"github.com/compspec/compspec-go/pkg/utils"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	cpuDevicesDir = "/sys/devices/system/cpu"
+)
+
+// A cache instance is shared by a set of cpus, and we count each once.
+// The size name is how sysfs writes it (e.g., 48K).
+type cache struct {
+	Name     string
+	SizeName string
+	Size     plugin.Value
+	LineSize int64
+	Shared   []int
+}
+
+// getTopologyInformation reads the topology of the cpus from sysfs, without
+// hwloc. A core is a (package, core id) pair, since core ids repeat across
+// sockets, and offline cpus (without a topology directory) are not counted.
+func getTopologyInformation(root rootfs.Root) (plugin.PluginSection, error) {
+	exists, err := root.Exists(cpuDevicesDir)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, plugin.Skipped("%s is not found", cpuDevicesDir)
+	}
+	info := plugin.PluginSection{}
+	for _, name := range []string{"online", "possible", "present"} {
+		cpus, err := readCPUList(root, filepath.Join(cpuDevicesDir, name))
+		if err == nil {
+			info[name] = plugin.String(utils.FormatCPUList(cpus))
+		}
+	}
+
+	paths, err := root.Glob(filepath.Join(cpuDevicesDir, "cpu[0-9]*"))
+	if err != nil {
+		return nil, err
+	}
+
+	// cpus in each socket, and siblings of each core in each socket
+	sockets := map[int64][]int{}
+	cores := map[int64]map[int64]string{}
+	caches := map[string]cache{}
+	threads := 0
+	for _, path := range paths {
+		cpu, err := strconv.Atoi(strings.TrimPrefix(filepath.Base(path), "cpu"))
+		if err != nil {
+			continue
+		}
+		socket, err := readInt(root, filepath.Join(path, "topology", "physical_package_id"))
+		if err != nil {
+			continue
+		}
+		core, err := readInt(root, filepath.Join(path, "topology", "core_id"))
+		if err != nil {
+			continue
+		}
+		threads += 1
+		sockets[socket] = append(sockets[socket], cpu)
+		if _, ok := cores[socket]; !ok {
+			cores[socket] = map[int64]string{}
+		}
+		siblings, err := readCPUList(root, filepath.Join(path, "topology", "thread_siblings_list"))
+		if err != nil {
+			siblings = []int{cpu}
+		}
+		cores[socket][core] = utils.FormatCPUList(siblings)
+
+		err = addCaches(root, path, caches)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if threads == 0 {
+		return nil, plugin.Skipped("no cpu topology was found in %s", cpuDevicesDir)
+	}
+
+	coreCount := 0
+	for socket, cpus := range sockets {
+		prefix := fmt.Sprintf("socket.%d.", socket)
+		info[prefix+"cpus"] = plugin.String(utils.FormatCPUList(cpus))
+		info[prefix+"cores"] = plugin.Int(int64(len(cores[socket])))
+		for core, siblings := range cores[socket] {
+			info[fmt.Sprintf("%score.%d.siblings", prefix, core)] = plugin.String(siblings)
+		}
+		coreCount += len(cores[socket])
+	}
+	info["sockets"] = plugin.Int(int64(len(sockets)))
+	info["cores"] = plugin.Int(int64(coreCount))
+	info["threads"] = plugin.Int(int64(threads))
+	info["threads_per_core"] = plugin.Int(int64(threads / coreCount))
+
+	// Each level of cache, how many there are, and for each size (a hybrid
+	// cpu can have more than one) how many, which cpus, and the most cpus
+	// that share one. Instances are grouped in order, so output is stable.
+	keys := []string{}
+	for key := range caches {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	counts := map[string]int64{}
+	sizes := map[string][]cache{}
+	for _, key := range keys {
+		instance := caches[key]
+		counts[instance.Name] += 1
+		prefix := "cache." + instance.Name + "." + instance.SizeName + "."
+		sizes[prefix] = append(sizes[prefix], instance)
+	}
+	for prefix, instances := range sizes {
+		cpus := []int{}
+		sharedBy := 0
+		for _, instance := range instances {
+			cpus = append(cpus, instance.Shared...)
+			if len(instance.Shared) > sharedBy {
+				sharedBy = len(instance.Shared)
+			}
+			if instance.LineSize > 0 {
+				info[prefix+"line_size"] = plugin.Int(instance.LineSize)
+			}
+		}
+		sort.Ints(cpus)
+		info[prefix+"size"] = instances[0].Size
+		info[prefix+"count"] = plugin.Int(int64(len(instances)))
+		info[prefix+"cpus"] = plugin.String(utils.FormatCPUList(cpus))
+		info[prefix+"shared_by"] = plugin.Int(int64(sharedBy))
+	}
+	for name, count := range counts {
+		info["cache."+name+".count"] = plugin.Int(count)
+		info["cache."+name+".sizes"] = plugin.List(cacheSizes(caches, name))
+	}
+
+	// The cpus that we can run on are only known for the host
+	if root.IsHost() {
+		allowed, err := getAllowedCPUs()
+		if err == nil {
+			info["allowed"] = plugin.String(utils.FormatCPUList(allowed))
+		}
+	}
+	return info, nil
+}
+
+// addCaches adds the caches of a cpu that we have not seen, keyed by
+// the cache name and the cpus that share it
+func addCaches(root rootfs.Root, path string, caches map[string]cache) error {
+	indexes, err := root.Glob(filepath.Join(path, "cache", "index[0-9]*"))
+	if err != nil {
+		return err
+	}
+	for _, index := range indexes {
+		level := readString(root, filepath.Join(index, "level"))
+		kind := readString(root, filepath.Join(index, "type"))
+		if level == "" {
+			continue
+		}
+		name := "L" + level
+		switch kind {
+		case "Data":
+			name += "d"
+		case "Instruction":
+			name += "i"
+		}
+		shared, err := readCPUList(root, filepath.Join(index, "shared_cpu_list"))
+		if err != nil {
+			continue
+		}
+		key := name + "/" + utils.FormatCPUList(shared)
+		if _, ok := caches[key]; ok {
+			continue
+		}
+
+		// Sizes are in KiB (e.g., 48K)
+		size, err := plugin.ParseBytes(readString(root, filepath.Join(index, "size")))
+		if err != nil {
+			continue
+		}
+		lineSize, _ := readInt(root, filepath.Join(index, "coherency_line_size"))
+		sizeName := strings.ReplaceAll(size.String(), " ", "")
+		caches[key] = cache{Name: name, SizeName: sizeName, Size: size, LineSize: lineSize, Shared: shared}
+	}
+	return nil
+}
+
+// cacheSizes returns the distinct sizes of caches at a level, smallest first
+func cacheSizes(caches map[string]cache, name string) []string {
+	bytes := map[string]int64{}
+	for _, instance := range caches {
+		if instance.Name == name {
+			bytes[instance.SizeName], _ = instance.Size.Bytes()
+		}
+	}
+	sizes := []string{}
+	for size := range bytes {
+		sizes = append(sizes, size)
+	}
+	sort.Slice(sizes, func(i, j int) bool {
+		if bytes[sizes[i]] != bytes[sizes[j]] {
+			return bytes[sizes[i]] < bytes[sizes[j]]
+		}
+		return sizes[i] < sizes[j]
+	})
+	return sizes
+}
+
+// getAllowedCPUs returns the cpus that this process is allowed to run on
+func getAllowedCPUs() ([]int, error) {
+	set := unix.CPUSet{}
+	err := unix.SchedGetaffinity(0, &set)
+	if err != nil {
+		return nil, err
+	}
+	cpus := []int{}
+	for cpu := 0; cpu < len(set)*64; cpu++ {
+		if set.IsSet(cpu) {
+			cpus = append(cpus, cpu)
+		}
+	}
+	return cpus, nil
+}
+
+// readCPUList reads a cpu list file (e.g., 0-3,8)
+func readCPUList(root rootfs.Root, path string) ([]int, error) {
+	raw, err := root.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return utils.ParseCPUList(string(raw))
+}
+
+// readInt reads a file with one integer
+func readInt(root rootfs.Root, path string) (int64, error) {
+	return strconv.ParseInt(readString(root, path), 10, 64)
+}
+
+// readString reads a file with one line, which is empty if it does not exist
+func readString(root rootfs.Root, path string) string {
+	raw, err := root.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(raw))
+}
@@ -0,0 +1,55 @@
+package system
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/compspec/compspec-go/pkg/plugin"
+	"github.com/compspec/compspec-go/pkg/rootfs"
+)
+
+var update = flag.Bool("update", false, "update the golden files in testdata")
+
+// TestTopology extracts the topology from a recorded tree of files for two
+// sockets, each with two cores of two threads, and with a different size of
+// L2 cache in each socket (as on a hybrid cpu)
+func TestTopology(t *testing.T) {
+	root, err := rootfs.New(filepath.Join("testdata", "topology"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	section, err := getTopologyInformation(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	compareGolden(t, filepath.Join("testdata", "topology.golden"), section)
+}
+
+// compareGolden compares the fields of a section (one per line) to a golden file
+func compareGolden(t *testing.T, golden string, section plugin.PluginSection) {
+	t.Helper()
+	lines := []string{}
+	for name, value := range section {
+		lines = append(lines, fmt.Sprintf("%s: %s (%s)", name, value, value.Type()))
+	}
+	sort.Strings(lines)
+	got := strings.Join(lines, "\n") + "\n"
+	if *update {
+		err := os.WriteFile(golden, []byte(got), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	expected, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != string(expected) {
+		t.Errorf("fields do not match %s\ngot:\n%s\nexpected:\n%s", golden, got, expected)
+	}
+}
@@ -1,24 +1,33 @@
 package plugins
 
 import (
+	"encoding/json"
+	"fmt"
 	"os"
+	"strings"
 
+	pg "github.com/compspec/compspec-go/pkg/plugin"
 	"github.com/jedib0t/go-pretty/v6/table"
 )
 
-// getPluginType returns a string to describe the plugin type
+// getPluginType describes the plugin type by the interfaces it implements,
+// since a registered plugin can be both an extractor and a creator
 func getPluginType(p PluginRequest) string {
 
-	if p.Plugin.IsCreator() && p.Plugin.IsExtractor() {
+	_, isExtractor := p.Extractor()
+	_, isCreator := p.Creator()
+
+	if isCreator && isExtractor {
 		return "extractor and creator"
 	}
-	if p.Plugin.IsExtractor() {
+	if isExtractor {
 		return "extractor"
 	}
 	return "creator"
 }
 
-// List plugins available, print in a pretty table!
+// List plugins in the request (all registered plugins by default), print
+// in a pretty table!
 func (r *PluginsRequest) List() error {
 
 	// Write out table with nodes
@@ -40,7 +49,7 @@ func (r *PluginsRequest) List() error {
 	// TODO add description column
 	for _, p := range *r {
 
-		if !p.Plugin.IsCreator() {
+		if _, ok := p.Creator(); !ok {
 			continue
 		}
 		pluginType := getPluginType(p)
@@ -57,20 +66,22 @@ func (r *PluginsRequest) List() error {
 	// TODO add description column
 	for _, p := range *r {
 
-		if p.Plugin.IsExtractor() {
-			extractorCount += 1
+		extractor, ok := p.Extractor()
+		if !ok {
+			continue
 		}
+		extractorCount += 1
 
 		newPlugin := true
 		pluginType := getPluginType(p)
 
 		// Extractors are parsed by sections
-		for _, section := range p.Plugin.Sections() {
+		for _, section := range extractor.Sections() {
 
 			// Add the extractor plugin description only for first in the list
 			if newPlugin {
 				t.AppendSeparator()
-				t.AppendRow(table.Row{p.Plugin.Description(), "", "", ""})
+				t.AppendRow(table.Row{extractor.Description(), "", "", ""})
 				newPlugin = false
 			}
 			count += 1
@@ -83,5 +94,85 @@ func (r *PluginsRequest) List() error {
 	t.AppendFooter(table.Row{"Total", "", extractorCount + creatorCount, count})
 	t.SetStyle(table.StyleColoredCyanWhiteOnBlack)
 	t.Render()
+	r.listOptions()
+	return nil
+}
+
+// listOptions prints the options that extractors declare, if any do
+func (r *PluginsRequest) listOptions() {
+	t := table.NewWriter()
+	t.SetTitle("Extractor Options")
+	t.SetOutputMirror(os.Stdout)
+	t.AppendHeader(table.Row{"Name", "Option", "Type", "Default", "Description"})
+
+	count := 0
+	for _, p := range *r {
+		if _, ok := p.Extractor(); !ok {
+			continue
+		}
+		registration, err := pg.Lookup(p.Name)
+		if err != nil {
+			continue
+		}
+		for _, option := range registration.Options {
+			optionType := option.Type
+			if optionType == "" {
+				optionType = pg.StringType
+			}
+			t.AppendRow(table.Row{p.Name, option.Name, optionType, option.Default, option.Description})
+			count += 1
+		}
+	}
+	if count == 0 {
+		return
+	}
+	t.AppendFooter(table.Row{"Total", "", "", "", count})
+	t.SetStyle(table.StyleColoredCyanWhiteOnBlack)
+	t.Render()
+}
+
+// ListFields prints the field catalog for extractors, as a table or json
+func (r *PluginsRequest) ListFields(asJson bool) error {
+	entries := []pg.CatalogEntry{}
+	missing := []string{}
+	for _, p := range *r {
+		extractor, ok := p.Extractor()
+		if !ok {
+			continue
+		}
+		catalog, ok := pg.Catalog(extractor)
+		if !ok {
+			missing = append(missing, p.Name)
+			continue
+		}
+		entries = append(entries, catalog...)
+	}
+
+	if asJson {
+		b, err := json.MarshalIndent(entries, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(b))
+		return nil
+	}
+
+	t := table.NewWriter()
+	t.SetTitle("Extractor Fields")
+	t.SetOutputMirror(os.Stdout)
+	t.AppendHeader(table.Row{"Field", "Type", "Unit", "Host Only", "Description", "Example"})
+	for _, entry := range entries {
+		hostOnly := ""
+		if entry.HostOnly {
+			hostOnly = "yes"
+		}
+		t.AppendRow(table.Row{entry.Field, entry.Type, entry.Unit, hostOnly, entry.Description, entry.Example})
+	}
+	t.AppendFooter(table.Row{"Total", "", "", "", "", len(entries)})
+	t.SetStyle(table.StyleColoredCyanWhiteOnBlack)
+	t.Render()
+	if len(missing) > 0 {
+		fmt.Printf("These extractors do not describe their fields: %s\n", strings.Join(missing, ", "))
+	}
 	return nil
 }
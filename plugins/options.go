@@ -0,0 +1,34 @@
+package plugins
+
+import (
+	pg "github.com/compspec/compspec-go/pkg/plugin"
+)
+
+// SetOptions parses and assigns options (raw values by plugin) to each
+// plugin request, and every plugin gets its defaults. Options for a plugin
+// that is not requested are still checked, so a typo is not ignored.
+func (r PluginsRequest) SetOptions(raw map[string]map[string]string) error {
+	for name, values := range raw {
+		registration, err := pg.Lookup(name)
+		if err != nil {
+			return err
+		}
+		_, err = pg.ParseOptions(name, registration.Options, values)
+		if err != nil {
+			return err
+		}
+	}
+	for i, p := range r {
+		registration, err := pg.Lookup(p.Name)
+		if err != nil {
+			return err
+		}
+		options, err := pg.ParseOptions(p.Name, registration.Options, raw[p.Name])
+		if err != nil {
+			return err
+		}
+		p.Options = options
+		r[i] = p
+	}
+	return nil
+}
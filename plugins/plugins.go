@@ -3,34 +3,7 @@ package plugins
 import (
 	"strings"
 
-	"github.com/compspec/compspec-go/plugins/extractors/kernel"
-	"github.com/compspec/compspec-go/plugins/extractors/library"
-	"github.com/compspec/compspec-go/plugins/extractors/nfd"
-	"github.com/compspec/compspec-go/plugins/extractors/system"
-
-	"github.com/compspec/compspec-go/plugins/creators/cluster"
-)
-
-// Add new plugin names here. They should correspond with the package name, then NewPlugin()
-var (
-	// Explicitly extractors
-	KernelExtractor  = "kernel"
-	SystemExtractor  = "system"
-	LibraryExtractor = "library"
-	NFDExtractor     = "nfd"
-
-	// Explicitly creators
-	ClusterCreator  = "cluster"
-	ArtifactCreator = "artifact"
-
-	pluginNames = []string{
-		ArtifactCreator,
-		ClusterCreator,
-		KernelExtractor,
-		SystemExtractor,
-		LibraryExtractor,
-		NFDExtractor,
-	}
+	"github.com/compspec/compspec-go/pkg/plugin"
 )
 
 // parseSections will return sections from the name string
@@ -43,8 +16,8 @@ func parseSections(raw string) (string, []string) {
 	if !strings.Contains(raw, "[") {
 		return raw, sections
 	}
-	// Get rid of last piece ]
-	raw = strings.ReplaceAll(raw, "]", "")
+	// Get rid of last piece ] (a field glob can have its own)
+	raw = strings.TrimSuffix(raw, "]")
 
 	// Split into two pieces, the
 	parts := strings.SplitN(raw, "[", 2)
@@ -55,82 +28,93 @@ func parseSections(raw string) (string, []string) {
 	return name, sections
 }
 
-// Get plugins parses a request and returns a list of extractor plugins
-// We honor the order that the plugins and sections are provided in
+// Get plugins parses a request and returns a list of plugins
+// We honor the order that the plugins and sections are provided in.
+// Plugins must be registered (see plugin.Register) to be found, and
+// the in-tree plugins are registered by importing plugins/builtin.
 func GetPlugins(names []string) (PluginsRequest, error) {
+	return SelectPlugins(names, []string{})
+}
 
-	if len(names) == 0 {
-		names = pluginNames
-	}
+// SelectPlugins is GetPlugins with plugins, sections, or fields to
+// exclude (e.g., kernel, system[processor], or nfd[cpu:cpuid.AVX*])
+func SelectPlugins(names, excludes []string) (PluginsRequest, error) {
 
 	request := PluginsRequest{}
 
-	// Prepare an extractor for each, and validate the requested sections
-	// TODO: this could also be done with an init -> Register pattern
-	for _, name := range names {
-
-		// If we are given a list of section names, parse.
-		name, sections := parseSections(name)
+	// Exclusions are merged into the selector for the same plugin
+	excluded := map[string][]Selector{}
+	for _, raw := range excludes {
+		exclude, err := ParseSelector(raw)
+		if err != nil {
+			return request, err
+		}
+		_, err = plugin.Lookup(exclude.Name)
+		if err != nil {
+			return request, err
+		}
+		excluded[exclude.Name] = append(excluded[exclude.Name], exclude)
+	}
 
-		if strings.HasPrefix(name, KernelExtractor) {
-			p, err := kernel.NewPlugin(sections)
-			if err != nil {
-				return request, err
-			}
-			// Save the name, the instantiated interface, and sections
-			pr := PluginRequest{Name: name, Plugin: p, Sections: sections}
-			request = append(request, pr)
+	// No names means all registered plugins
+	if len(names) == 0 {
+		for _, r := range plugin.Registered() {
+			names = append(names, r.Name)
 		}
+	}
 
-		// Cluster and artifact creators
-		if strings.HasPrefix(name, ClusterCreator) {
-			p, err := cluster.NewPlugin()
-			if err != nil {
-				return request, err
-			}
-			// Save the name, the instantiated interface, and sections
-			pr := PluginRequest{Name: name, Plugin: p}
-			request = append(request, pr)
+	// Prepare a plugin for each, and validate the requested sections
+	for _, name := range names {
+
+		// If we are given a list of section names (or fields), parse.
+		selector, err := ParseSelector(name)
+		if err != nil {
+			return request, err
 		}
-		if strings.HasPrefix(name, ArtifactCreator) {
-			p, err := cluster.NewPlugin()
-			if err != nil {
-				return request, err
-			}
-			// Save the name, the instantiated interface, and sections
-			pr := PluginRequest{Name: name, Plugin: p}
-			request = append(request, pr)
+		registration, err := plugin.Lookup(selector.Name)
+		if err != nil {
+			return request, err
 		}
 
-		if strings.HasPrefix(name, NFDExtractor) {
-			p, err := nfd.NewPlugin(sections)
-			if err != nil {
-				return request, err
+		// An entire plugin can be excluded
+		skip := false
+		for _, exclude := range excluded[selector.Name] {
+			if exclude.IsPlugin() {
+				skip = true
+				break
 			}
-			// Save the name, the instantiated interface, and sections
-			pr := PluginRequest{Name: name, Plugin: p, Sections: sections}
-			request = append(request, pr)
-		}
-
-		if strings.HasPrefix(name, SystemExtractor) {
-			p, err := system.NewPlugin(sections)
+			err := selector.Exclude(exclude)
 			if err != nil {
 				return request, err
 			}
-			// Save the name, the instantiated interface, and sections
-			pr := PluginRequest{Name: name, Plugin: p, Sections: sections}
-			request = append(request, pr)
+		}
+		if skip {
+			continue
 		}
 
-		if strings.HasPrefix(name, LibraryExtractor) {
-			p, err := library.NewPlugin(sections)
+		// Excluding sections needs the sections the plugin knows
+		known := registration.Sections
+		if len(selector.Excluded) > 0 && len(known) == 0 {
+			p, err := registration.New([]string{})
 			if err != nil {
 				return request, err
 			}
-			// Save the name, the instantiated interface, and sections
-			pr := PluginRequest{Name: name, Plugin: p, Sections: sections}
-			request = append(request, pr)
+			if extractor, ok := p.(plugin.Extractor); ok {
+				known = extractor.Sections()
+			}
+		}
+		sections, err := selector.Resolve(known)
+		if err != nil {
+			return request, err
 		}
+		p, err := registration.New(sections)
+		if err != nil {
+			return request, err
+		}
+
+		// Save the name, the instantiated interface, and sections
+		pr := PluginRequest{Name: selector.Name, Plugin: p, Sections: sections, Selector: selector}
+		request = append(request, pr)
 	}
 	return request, nil
 }
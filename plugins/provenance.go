@@ -0,0 +1,128 @@
+package plugins
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	pg "github.com/compspec/compspec-go/pkg/plugin"
+	"github.com/compspec/compspec-go/pkg/types"
+)
+
+const (
+	bootIDFile = "/proc/sys/kernel/random/boot_id"
+	cgroupFile = "/proc/1/cgroup"
+)
+
+var (
+	// The machine id is usually in /etc, and older systems have it for dbus
+	machineIDFiles = []string{"/etc/machine-id", "/var/lib/dbus/machine-id"}
+
+	// Files that container runtimes create in the container
+	containerFiles = [][2]string{
+		{"/.dockerenv", "docker"},
+		{"/run/.containerenv", "podman"},
+		{"/.singularity.d", "singularity"},
+	}
+
+	// Names in the cgroup of init that are specific to a runtime,
+	// checked in order (kubernetes uses containerd or docker)
+	containerCgroups = [][2]string{
+		{"kubepods", "kubernetes"},
+		{"docker", "docker"},
+		{"libpod", "podman"},
+		{"containerd", "containerd"},
+		{"lxc", "lxc"},
+	}
+)
+
+// provenance describes the extraction that is started for the request.
+// This is about the host that we are running on, even with a root, and
+// the root or image is recorded too.
+func (r *PluginsRequest) provenance(start time.Time, options ExtractOptions) *pg.Provenance {
+	p := pg.Provenance{
+		Timestamp: start.UTC(),
+		Version:   types.Version,
+		Command:   os.Args,
+		MachineID: readID(machineIDFiles...),
+		BootID:    readID(bootIDFile),
+		Container: detectContainer(),
+		Plugins:   map[string]string{},
+	}
+	hostname, err := os.Hostname()
+	if err == nil {
+		p.Hostname = hostname
+	}
+	if options.Image != "" {
+		p.Image = options.Image
+	} else if !options.Root.IsHost() {
+		p.Root = options.Root.String()
+	}
+	for _, request := range *r {
+		if _, ok := request.Extractor(); !ok {
+			continue
+		}
+		p.Requested = append(p.Requested, request.String())
+		p.Plugins[request.Name] = pg.VersionOf(request.Plugin)
+	}
+	return &p
+}
+
+// String returns the request as it would be asked for, e.g., system[os,arch]
+func (r *PluginRequest) String() string {
+	if r.Selector.Name != "" {
+		return r.Selector.String()
+	}
+	if len(r.Sections) == 0 {
+		return r.Name
+	}
+	return fmt.Sprintf("%s[%s]", r.Name, strings.Join(r.Sections, ","))
+}
+
+// readID reads the first identifier file that exists
+func readID(paths ...string) string {
+	for _, path := range paths {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		id := strings.TrimSpace(string(content))
+		if id != "" {
+			return id
+		}
+	}
+	return ""
+}
+
+// detectContainer returns the container runtime we are running in, or
+// an empty string if we are not in a container (or cannot tell)
+func detectContainer() string {
+
+	// systemd-nspawn, podman, and others tell us directly
+	name := os.Getenv("container")
+	if name != "" {
+		return name
+	}
+	if os.Getenv("KUBERNETES_SERVICE_HOST") != "" {
+		return "kubernetes"
+	}
+	for _, file := range containerFiles {
+		if _, err := os.Stat(file[0]); err == nil {
+			return file[1]
+		}
+	}
+
+	// Otherwise the cgroup of init can give away the runtime
+	content, err := os.ReadFile(cgroupFile)
+	if err != nil {
+		return ""
+	}
+	cgroup := string(content)
+	for _, runtime := range containerCgroups {
+		if strings.Contains(cgroup, runtime[0]) {
+			return runtime[1]
+		}
+	}
+	return ""
+}
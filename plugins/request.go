@@ -1,49 +1,315 @@
 package plugins
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
 
-	"github.com/compspec/compspec-go/pkg/plugin"
 	pg "github.com/compspec/compspec-go/pkg/plugin"
+	"github.com/compspec/compspec-go/pkg/redact"
+	"github.com/compspec/compspec-go/pkg/rootfs"
+	"github.com/compspec/compspec-go/pkg/schema"
 )
 
 // A plugin request has a Name and sections
 type PluginRequest struct {
 	Name     string
 	Sections []string
-	Plugin   pg.PluginInterface
+	Plugin   pg.Plugin
+
+	// What to include or exclude (e.g., fields) as it was asked for
+	Selector Selector
+
+	// Optional timeouts for the plugin and named sections
+	Timeout         time.Duration
+	SectionTimeouts map[string]time.Duration
+
+	// Options for the plugin, which an extractor gets from the context
+	Options pg.Options
+}
+
+// Extractor returns the plugin as an extractor, if it is one
+func (r *PluginRequest) Extractor() (pg.Extractor, bool) {
+	e, ok := r.Plugin.(pg.Extractor)
+	return e, ok
+}
+
+// Creator returns the plugin as a creator, if it is one
+func (r *PluginRequest) Creator() (pg.Creator, bool) {
+	c, ok := r.Plugin.(pg.Creator)
+	return c, ok
 }
 
 type PluginsRequest []PluginRequest
 
+// ExtractOptions control how extraction is run across plugins
+type ExtractOptions struct {
+
+	// Allow any plugin (or section) to fail and continue extraction
+	AllowFail bool
+
+	// The number of plugins or sections to extract at once
+	// Zero or one means that extraction is serial.
+	Jobs int
+
+	// The filesystem to extract from, which defaults to the host, and
+	// the image it was unpacked from (if any)
+	Root  rootfs.Root
+	Image string
+
+	// Fields to redact before the result is printed or saved
+	Redact redact.Policy
+
+	// Check the result against the schema of each extractor (before
+	// redaction, which can change the type of a value)
+	Validate bool
+}
+
+// An extractTask is one unit of extraction work, either a single section
+// for a plugin that supports it, or an entire plugin.
+type extractTask struct {
+	ctx       context.Context
+	request   *PluginRequest
+	extractor pg.Extractor
+
+	// An empty section means the entire plugin
+	section string
+
+	// Results of the task
+	data     pg.PluginData
+	sectData pg.PluginSection
+	err      error
+	duration time.Duration
+}
+
+// run performs the extraction for the task, honoring a section timeout
+func (t *extractTask) run(allowFail bool) {
+	start := time.Now()
+	defer func() { t.duration = time.Since(start) }()
+
+	// An image does not have a running system to describe
+	if t.hostOnly() {
+		t.err = pg.Unsupported("%s describes a running system and cannot be extracted from an image", t.name())
+		return
+	}
+
+	// Without sections, the plugin timeout is the best we can do
+	if t.section == "" {
+		data, err := pg.Extract(t.ctx, t.extractor, allowFail)
+		t.data, t.err = data, timeoutError(err, t.request.Timeout)
+		return
+	}
+
+	timeout, ok := t.request.SectionTimeouts[t.section]
+	if !ok {
+		timeout = t.request.Timeout
+	}
+	ctx, cancel := withTimeout(t.ctx, t.request.SectionTimeouts[t.section])
+	defer cancel()
+
+	// A timeout or other failure is a failure of the section
+	section, err := pg.ExtractSection(ctx, t.extractor.(pg.SectionExtractor), t.section)
+	t.sectData, t.err = section, timeoutError(err, timeout)
+}
+
+// hostOnly determines if the task is for an image, and only describes a
+// running system. An entire plugin is only if all of its sections are.
+func (t *extractTask) hostOnly() bool {
+	if !rootfs.FromContext(t.ctx).IsImage() {
+		return false
+	}
+	hostOnly, ok := t.extractor.(pg.HostOnly)
+	if !ok {
+		return false
+	}
+	if t.section != "" {
+		return hostOnly.HostOnly(t.section)
+	}
+	for _, name := range t.extractor.Sections() {
+		if !hostOnly.HostOnly(name) {
+			return false
+		}
+	}
+	return true
+}
+
+// name is the plugin and section (if there is one) for messages
+func (t *extractTask) name() string {
+	if t.section == "" {
+		return t.request.Name
+	}
+	return fmt.Sprintf("%s[%s]", t.request.Name, t.section)
+}
+
+// pluginStatus fills in the status of each section for an entire plugin.
+// The plugin can provide its own, and otherwise a section we have data
+// for is ok, and any other section has the status of the error.
+func (t *extractTask) pluginStatus(err error) {
+	for _, name := range t.extractor.Sections() {
+		if _, ok := t.data.Status[name]; ok {
+			continue
+		}
+		status := pg.NewStatus(err, t.duration, pg.SourceOf(t.extractor, name))
+		if _, ok := t.data.Sections[name]; ok {
+			status = pg.NewStatus(nil, t.duration, status.Source)
+		} else if err == nil {
+			status = pg.NewStatus(pg.Skipped("no data was extracted"), t.duration, status.Source)
+		}
+		t.data.SetStatus(name, status)
+	}
+}
+
 // Do the extraction for a plugin request, meaning across a set of plugins
-func (r *PluginsRequest) Extract(allowFail bool) (pg.Result, error) {
+// The context can carry a global deadline, and is cancelled on interrupt.
+// Plugins (and sections) can be extracted concurrently, and the result is
+// assembled in the order of the request, so it is the same as a serial run.
+func (r *PluginsRequest) Extract(ctx context.Context, options ExtractOptions) (pg.Result, error) {
 
-	// Prepare Result
+	provenance := r.provenance(time.Now(), options)
+
+	// We cancel remaining work if we are not allowing failure
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Extractors find the root in the context
+	ctx = rootfs.WithRoot(ctx, options.Root)
+
+	// Prepare tasks, with a context for each plugin (e.g., with a timeout)
+	tasks := []*extractTask{}
+	for i := range *r {
+		p := &(*r)[i]
+
+		// Skip plugins that don't define extraction
+		extractor, ok := p.Extractor()
+		if !ok {
+			continue
+		}
+		pluginCtx, pluginCancel := withTimeout(ctx, p.Timeout)
+		defer pluginCancel()
+		pluginCtx = pg.WithOptions(pluginCtx, p.Options)
+
+		// If the plugin can extract one section at a time, we do that
+		if _, ok := extractor.(pg.SectionExtractor); ok {
+			for _, name := range extractor.Sections() {
+				task := extractTask{ctx: pluginCtx, request: p, extractor: extractor, section: name}
+				tasks = append(tasks, &task)
+			}
+			continue
+		}
+		task := extractTask{ctx: pluginCtx, request: p, extractor: extractor}
+		tasks = append(tasks, &task)
+	}
+
+	// Run the tasks with a bounded number of workers
+	jobs := options.Jobs
+	if jobs < 1 {
+		jobs = 1
+	}
+	var wg sync.WaitGroup
+	var aborted atomic.Bool
+	workers := make(chan struct{}, jobs)
+	for _, task := range tasks {
+		wg.Add(1)
+		workers <- struct{}{}
+		go func(task *extractTask) {
+			defer wg.Done()
+			defer func() { <-workers }()
+			task.run(options.AllowFail)
+
+			// Stop the remaining work on the first failure
+			if pg.IsFailure(task.err) && !options.AllowFail {
+				aborted.Store(true)
+				cancel()
+			}
+		}(task)
+	}
+	wg.Wait()
+
+	// Prepare Result in the order of the request
 	result := pg.Result{}
 	results := map[string]pg.PluginData{}
+	data := map[*PluginRequest]pg.PluginData{}
 
-	for _, p := range *r {
+	for _, task := range tasks {
+		err := task.err
 
-		// Skip plugins that don't define extraction
-		if !p.Plugin.IsExtractor() {
+		// Tasks that we cancelled are not the cause of the failure
+		if aborted.Load() && errors.Is(err, context.Canceled) {
+			err = pg.Skipped("extraction was stopped after another failure")
+		}
+		if pg.IsFailure(err) {
+			if !options.AllowFail {
+				return result, fmt.Errorf("there was an extraction error for %s: %s", task.request.Name, err)
+			}
+			// The status has the error, so this is just a note (not on stdout, which
+			// might be the result)
+			if task.section == "" {
+				fmt.Fprintf(os.Stderr, "Allowing failure - ignoring extraction error for %s: %s\n", task.request.Name, err)
+			} else {
+				fmt.Fprintf(os.Stderr, "Allowing failure - ignoring extraction error for %s[%s]: %s\n", task.request.Name, task.section, err)
+			}
+		}
+
+		// An entire plugin
+		if task.section == "" {
+			for name, section := range task.data.Sections {
+				task.data.Sections[name] = task.request.Selector.Filter(name, section)
+			}
+			task.pluginStatus(err)
+			data[task.request] = task.data
 			continue
 		}
-		// We can allow failure on the level of the sections
-		r, err := p.Plugin.Extract(allowFail)
-
-		// We can allow failure
-		if err != nil && !allowFail {
-			return result, fmt.Errorf("there was an extraction error for %s: %s", p.Name, err)
-		} else if err != nil && allowFail {
-			fmt.Printf("Allowing failure - ignoring extraction error for %s: %s\n", p.Name, err)
+
+		// Or a section of one
+		pluginData, ok := data[task.request]
+		if !ok {
+			pluginData = pg.PluginData{Sections: pg.Sections{}}
+		}
+		if task.sectData != nil {
+			pluginData.Sections[task.section] = task.request.Selector.Filter(task.section, task.sectData)
 		}
-		results[p.Name] = r
+		source := pg.SourceOf(task.extractor, task.section)
+		pluginData.SetStatus(task.section, pg.NewStatus(err, task.duration, source))
+		data[task.request] = pluginData
+	}
+	for _, task := range tasks {
+		results[task.request.Name] = data[task.request]
 	}
 	result.Results = results
+	result.Provenance = provenance
+	if options.Validate {
+		err := schema.Validate(&result)
+		if err != nil {
+			return result, err
+		}
+	}
+	options.Redact.Apply(&result)
 	return result, nil
 }
 
+// withTimeout returns a context with a timeout, if one is set
+func withTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout == 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// timeoutError makes an error from a deadline more descriptive
+func timeoutError(err error, timeout time.Duration) error {
+	if err == nil || !errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+	if timeout == 0 {
+		return fmt.Errorf("%s (extraction timed out)", err)
+	}
+	return fmt.Errorf("%s (timed out after %s)", err, timeout)
+}
+
 // Do creation
 func (r *PluginsRequest) Create() (pg.Result, error) {
 
@@ -52,12 +318,13 @@ func (r *PluginsRequest) Create() (pg.Result, error) {
 
 	for _, p := range *r {
 
-		// Skip plugins that don't define extraction
-		if !p.Plugin.IsCreator() {
+		// Skip plugins that don't define creation
+		creator, ok := p.Creator()
+		if !ok {
 			continue
 		}
-		options := plugin.PluginOptions{}
-		err := p.Plugin.Create(options)
+		options := pg.PluginOptions{}
+		err := creator.Create(options)
 		if err != nil {
 			return result, err
 		}
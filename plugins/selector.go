@@ -0,0 +1,191 @@
+package plugins
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	pg "github.com/compspec/compspec-go/pkg/plugin"
+)
+
+// A Selector is a parsed plugin name (as given to --name or --exclude)
+// with the sections and fields to include or exclude, e.g.,
+//
+//	system[os,arch]          only the os and arch sections
+//	system[-processor]       every section except processor
+//	nfd[cpu:cpuid.*]         the cpu section, with fields matching cpuid.*
+//	nfd[cpu,-cpu:cpuid.AVX*] the cpu section, without fields matching cpuid.AVX*
+type Selector struct {
+	Name string
+
+	// Sections to include (empty means all) and exclude
+	Sections []string
+	Excluded []string
+
+	// Field globs to include and exclude, by section
+	Fields         map[string][]string
+	ExcludedFields map[string][]string
+}
+
+// ParseSelector parses a plugin name with optional sections and fields
+func ParseSelector(raw string) (Selector, error) {
+	name, items := parseSections(raw)
+	s := Selector{Name: name, Fields: map[string][]string{}, ExcludedFields: map[string][]string{}}
+	for _, item := range items {
+		exclude := strings.HasPrefix(item, "-")
+		item = strings.TrimPrefix(item, "-")
+		section, glob, hasGlob := strings.Cut(item, ":")
+		if section == "" || (hasGlob && glob == "") {
+			return s, fmt.Errorf("%s is not a valid selector, expected name[section,-section,section:field-glob]", raw)
+		}
+		if hasGlob {
+			if _, err := path.Match(glob, ""); err != nil {
+				return s, fmt.Errorf("field glob %s in %s is not valid: %s", glob, raw, err)
+			}
+		}
+		switch {
+		case exclude && hasGlob:
+			s.ExcludedFields[section] = append(s.ExcludedFields[section], glob)
+		case exclude:
+			s.Excluded = append(s.Excluded, section)
+		case hasGlob:
+			s.Fields[section] = append(s.Fields[section], glob)
+			s.addSection(section)
+		default:
+			s.addSection(section)
+		}
+	}
+	return s, nil
+}
+
+// addSection adds a section to include, once
+func (s *Selector) addSection(section string) {
+	for _, existing := range s.Sections {
+		if existing == section {
+			return
+		}
+	}
+	s.Sections = append(s.Sections, section)
+}
+
+// Exclude adds what an --exclude selector (for the same plugin) excludes.
+// Sections and fields in an exclude are what to leave out.
+func (s *Selector) Exclude(exclude Selector) error {
+	if len(exclude.Excluded) > 0 || len(exclude.ExcludedFields) > 0 {
+		return fmt.Errorf("exclude for %s should list what to leave out, without a -", exclude.Name)
+	}
+	for _, section := range exclude.Sections {
+		if len(exclude.Fields[section]) == 0 {
+			s.Excluded = append(s.Excluded, section)
+		}
+	}
+	for section, globs := range exclude.Fields {
+		s.ExcludedFields[section] = append(s.ExcludedFields[section], globs...)
+	}
+	return nil
+}
+
+// IsPlugin determines if the selector is the entire plugin
+func (s *Selector) IsPlugin() bool {
+	return len(s.Sections) == 0 && len(s.Excluded) == 0 && len(s.Fields) == 0 && len(s.ExcludedFields) == 0
+}
+
+// Resolve returns the sections to extract, given the known sections for
+// the plugin. Nil means all of them.
+func (s *Selector) Resolve(known []string) ([]string, error) {
+	if len(s.Excluded) == 0 {
+		return s.Sections, nil
+	}
+	sections := s.Sections
+	if len(sections) == 0 {
+		sections = known
+	}
+	excluded := map[string]bool{}
+	for _, section := range s.Excluded {
+		if !contains(known, section) {
+			return nil, fmt.Errorf("section %s is not known for plugin %s", section, s.Name)
+		}
+		excluded[section] = true
+	}
+	resolved := []string{}
+	for _, section := range sections {
+		if !excluded[section] {
+			resolved = append(resolved, section)
+		}
+	}
+	if len(resolved) == 0 {
+		return nil, fmt.Errorf("every section of plugin %s is excluded", s.Name)
+	}
+	return resolved, nil
+}
+
+// Filter returns the fields in a section that are selected
+func (s *Selector) Filter(section string, data pg.PluginSection) pg.PluginSection {
+	include := s.Fields[section]
+	exclude := s.ExcludedFields[section]
+	if data == nil || (len(include) == 0 && len(exclude) == 0) {
+		return data
+	}
+	filtered := pg.PluginSection{}
+	for field, value := range data {
+		if len(include) > 0 && !matchAny(include, field) {
+			continue
+		}
+		if matchAny(exclude, field) {
+			continue
+		}
+		filtered[field] = value
+	}
+	return filtered
+}
+
+// String returns the selector as it would be asked for
+func (s *Selector) String() string {
+	items := []string{}
+	for _, section := range s.Sections {
+		if len(s.Fields[section]) == 0 {
+			items = append(items, section)
+		}
+		for _, glob := range s.Fields[section] {
+			items = append(items, section+":"+glob)
+		}
+	}
+	for _, section := range s.Excluded {
+		items = append(items, "-"+section)
+	}
+	sections := []string{}
+	for section := range s.ExcludedFields {
+		sections = append(sections, section)
+	}
+	sort.Strings(sections)
+	for _, section := range sections {
+		for _, glob := range s.ExcludedFields[section] {
+			items = append(items, "-"+section+":"+glob)
+		}
+	}
+	if len(items) == 0 {
+		return s.Name
+	}
+	return fmt.Sprintf("%s[%s]", s.Name, strings.Join(items, ","))
+}
+
+// matchAny determines if a field matches any glob
+func matchAny(globs []string, field string) bool {
+	for _, glob := range globs {
+		if pg.MatchGlob(glob, field) {
+			return true
+		}
+	}
+	return false
+}
+
+// contains determines if a list has an item
+func contains(items []string, item string) bool {
+	for _, existing := range items {
+		if existing == item {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,129 @@
+package plugins
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	pg "github.com/compspec/compspec-go/pkg/plugin"
+)
+
+// Timeouts hold a global timeout for an entire extraction, along with
+// overrides for named plugins and plugin sections.
+type Timeouts struct {
+	Global   time.Duration
+	Plugins  map[string]time.Duration
+	Sections map[string]map[string]time.Duration
+}
+
+// ParseTimeouts parses timeout specifications. Each is either a duration
+// for the global timeout (e.g., 2m) or a plugin selector in the same format
+// as --name and a duration (e.g., library=30s or library[mpi]=5s)
+func ParseTimeouts(specs []string) (Timeouts, error) {
+
+	timeouts := Timeouts{
+		Plugins:  map[string]time.Duration{},
+		Sections: map[string]map[string]time.Duration{},
+	}
+	for _, spec := range specs {
+
+		// No name means this is the global timeout
+		if !strings.Contains(spec, "=") {
+			duration, err := parseTimeout(spec)
+			if err != nil {
+				return timeouts, err
+			}
+			timeouts.Global = duration
+			continue
+		}
+
+		parts := strings.SplitN(spec, "=", 2)
+		duration, err := parseTimeout(parts[1])
+		if err != nil {
+			return timeouts, err
+		}
+
+		// Without sections, the timeout is for the whole plugin
+		selector, err := ParseSelector(parts[0])
+		if err != nil {
+			return timeouts, err
+		}
+		name, sections := selector.Name, selector.Sections
+		if len(sections) == 0 {
+			timeouts.Plugins[name] = duration
+			continue
+		}
+		_, ok := timeouts.Sections[name]
+		if !ok {
+			timeouts.Sections[name] = map[string]time.Duration{}
+		}
+		for _, section := range sections {
+			timeouts.Sections[name][section] = duration
+		}
+	}
+	return timeouts, nil
+}
+
+// parseTimeout parses a single duration, which must be positive
+func parseTimeout(value string) (time.Duration, error) {
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return duration, fmt.Errorf("timeout %s is not a valid duration (e.g., 30s or 2m): %s", value, err)
+	}
+	if duration <= 0 {
+		return duration, fmt.Errorf("timeout %s must be greater than zero", value)
+	}
+	return duration, nil
+}
+
+// SetTimeouts assigns plugin and section timeouts to each plugin request.
+// The global timeout is applied by the caller on the extraction context.
+// Timeouts for a plugin (or section) that is not known are an error, so a
+// typo is not ignored.
+func (r PluginsRequest) SetTimeouts(timeouts Timeouts) error {
+	for name := range timeouts.Plugins {
+		_, err := pg.Lookup(name)
+		if err != nil {
+			return fmt.Errorf("timeout for %s: %s", name, err)
+		}
+	}
+	for name, sections := range timeouts.Sections {
+		known, err := knownSections(name)
+		if err != nil {
+			return fmt.Errorf("timeout for %s: %s", name, err)
+		}
+		for section := range sections {
+			if !contains(known, section) {
+				return fmt.Errorf("timeout for %s[%s]: section %s is not known, known sections are %s", name, section, section, strings.Join(known, ", "))
+			}
+		}
+	}
+	for i, p := range r {
+		p.Timeout = timeouts.Plugins[p.Name]
+		p.SectionTimeouts = timeouts.Sections[p.Name]
+		r[i] = p
+	}
+	return nil
+}
+
+// knownSections returns the sections of a registered plugin, which for a
+// plugin that describes itself (e.g., an external extractor) come from
+// the plugin
+func knownSections(name string) ([]string, error) {
+	registration, err := pg.Lookup(name)
+	if err != nil {
+		return nil, err
+	}
+	if len(registration.Sections) > 0 {
+		return registration.Sections, nil
+	}
+	p, err := pg.Described(name)
+	if err != nil {
+		return nil, err
+	}
+	extractor, ok := p.(pg.Extractor)
+	if !ok {
+		return nil, fmt.Errorf("plugin %s does not have sections", name)
+	}
+	return extractor.Sections(), nil
+}